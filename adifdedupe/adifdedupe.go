@@ -4,13 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"github.com/Matir/adifparser"
-	"io"
 	"os"
 )
 
 func main() {
 	var infile = flag.String("infile", "", "Input file.")
 	var outfile = flag.String("outfile", "", "Output file.")
+	var lenient = flag.Bool("lenient", false, "Skip malformed records instead of stopping.")
+	var comments = flag.Bool("comments", false, "Keep comments between fields.")
 
 	flag.Parse()
 
@@ -25,28 +26,42 @@ func main() {
 		return
 	}
 
+	var opts []adifparser.ReaderOption
+	// Copy values unchanged, including non-ASCII text in any field
+	writerOpts := []adifparser.WriterOption{adifparser.WithOutputCharset(adifparser.CharsetUTF8)}
+	if *comments {
+		opts = append(opts, adifparser.WithComments())
+		writerOpts = append(writerOpts, adifparser.WithOutputComments())
+	}
+
 	var writer adifparser.ADIFWriter
 	var writefp *os.File
 	if *outfile != "" {
 		writefp, err = os.Create(*outfile)
-		writer = adifparser.NewADIFWriter(writefp)
+		writer = adifparser.NewADIFWriter(writefp, writerOpts...)
 	} else {
 		writefp = nil
-		writer = adifparser.NewADIFWriter(os.Stdout)
+		writer = adifparser.NewADIFWriter(os.Stdout, writerOpts...)
 	}
 
-	reader := adifparser.NewDedupeADIFReader(fp)
-	for record, err := reader.ReadRecord(); record != nil || err != nil; record, err = reader.ReadRecord() {
+	if *lenient {
+		opts = append(opts, adifparser.WithRecovery(func(skipped adifparser.SkippedRecord) {
+			fmt.Fprintf(os.Stderr, "Skipped record: %v\n", skipped.Err)
+		}))
+	}
+	reader := adifparser.NewDedupeReader(adifparser.NewAutoReader(fp, opts...))
+	for record, err := range reader.All() {
 		if err != nil {
-			if err != io.EOF {
-				fmt.Fprint(os.Stderr, err)
-			}
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		if err := writer.WriteRecord(record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			break
 		}
-		writer.WriteRecord(record)
 	}
 
-	writer.Flush()
+	writer.Close()
 
 	if writefp != nil {
 		writefp.Close()
@@ -2,8 +2,17 @@ package adifparser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
+// ADIF version written by this package
+const ADIFVersion = "3.1.4"
+
+// Program id written by this package
+const defaultProgramID = "adifparser"
+
+// ADIF data types
 const (
 	ADIFBoolean = iota
 	ADIFNumber
@@ -11,158 +20,355 @@ const (
 	ADIFDate
 	ADIFTime
 	ADIFLocation
+	ADIFInteger
+	ADIFPositiveInteger
+	ADIFEnumeration
+	ADIFCharacter
+	ADIFDigit
+	ADIFIntlString
+	ADIFMultilineString
+	ADIFIntlMultilineString
+	ADIFGridSquare
+	ADIFGridSquareExt
+	ADIFGridSquareList
+	ADIFIOTARefNo
+	ADIFSOTARef
+	ADIFPOTARef
+	ADIFPOTARefList
+	ADIFWWFFRef
+	ADIFCreditList
+	ADIFSponsoredAwardList
+	ADIFSecondarySubdivisionList
 )
 
-type fieldMetadata struct {
-	name     string
-	datatype int
+var adifTypeNames = map[int]string{
+	ADIFBoolean:                  "Boolean",
+	ADIFNumber:                   "Number",
+	ADIFString:                   "String",
+	ADIFDate:                     "Date",
+	ADIFTime:                     "Time",
+	ADIFLocation:                 "Location",
+	ADIFInteger:                  "Integer",
+	ADIFPositiveInteger:          "PositiveInteger",
+	ADIFEnumeration:              "Enumeration",
+	ADIFCharacter:                "Character",
+	ADIFDigit:                    "Digit",
+	ADIFIntlString:               "IntlString",
+	ADIFMultilineString:          "MultilineString",
+	ADIFIntlMultilineString:      "IntlMultilineString",
+	ADIFGridSquare:               "GridSquare",
+	ADIFGridSquareExt:            "GridSquareExt",
+	ADIFGridSquareList:           "GridSquareList",
+	ADIFIOTARefNo:                "IOTARefNo",
+	ADIFSOTARef:                  "SOTARef",
+	ADIFPOTARef:                  "POTARef",
+	ADIFPOTARefList:              "POTARefList",
+	ADIFWWFFRef:                  "WWFFRef",
+	ADIFCreditList:               "CreditList",
+	ADIFSponsoredAwardList:       "SponsoredAwardList",
+	ADIFSecondarySubdivisionList: "SecondarySubdivisionList",
 }
 
+// Data type indicators
 var typeCodeMap = map[byte]int{
 	'A': ADIFString,
 	'B': ADIFBoolean,
 	'N': ADIFNumber,
 	'S': ADIFString,
+	'I': ADIFIntlString,
 	'D': ADIFDate,
 	'T': ADIFTime,
-	'M': ADIFString,
+	'M': ADIFMultilineString,
+	'G': ADIFIntlMultilineString,
+	'E': ADIFEnumeration,
 	'L': ADIFLocation,
 }
 
+// Definition of a standard ADIF field
+type FieldInfo struct {
+	// Field name (lowercase)
+	Name string
+	// Data type (ADIFString, ADIFNumber, ...)
+	DataType int
+	// Name of the enumeration the value is taken from, if any
+	Enumeration string
+	// ADIF version that introduced the field.  Fields from ADIF 2 are
+	// all recorded as 2.0.0.
+	Introduced string
+	// ADIF version that deprecated the field, if any
+	Deprecated string
+	// Whether the field may only be imported, not exported
+	ImportOnly bool
+}
+
+// Whether the field is part of the given ADIF version
+func (f FieldInfo) InVersion(version string) bool {
+	if compareVersions(version, f.Introduced) < 0 {
+		return false
+	}
+	return f.Deprecated == "" || compareVersions(version, f.Deprecated) < 0
+}
+
+// Whether the field holds international (non-ASCII) characters
+func (f FieldInfo) IsIntl() bool {
+	return f.DataType == ADIFIntlString || f.DataType == ADIFIntlMultilineString
+}
+
+// Standard field names in output order
 var ADIFfieldOrder []string
-var ADIFfieldInfo map[string]fieldMetadata
 
-func addField(name string, datatype int) {
+// Standard field definitions by name
+var ADIFfieldInfo map[string]FieldInfo
+
+// Get the definition of a standard field
+func LookupField(name string) (FieldInfo, bool) {
+	info, ok := ADIFfieldInfo[strings.ToLower(name)]
+	return info, ok
+}
+
+// Get the definitions of all fields in the given ADIF version, in output
+// order
+func FieldsForVersion(version string) []FieldInfo {
+	fields := make([]FieldInfo, 0, len(ADIFfieldOrder))
 	for _, n := range ADIFfieldOrder {
-		if name == n {
-			panic(fmt.Sprintf("Duplicate field name %s.", name))
+		if info := ADIFfieldInfo[n]; info.InVersion(version) {
+			fields = append(fields, info)
 		}
 	}
-	ADIFfieldOrder = append(ADIFfieldOrder, name)
-	ADIFfieldInfo[name] = fieldMetadata{name, datatype}
+	return fields
 }
 
-func isStandardADIFField(name string) bool {
-	for _, n := range ADIFfieldOrder {
-		if name == n {
-			return true
+// Get the name of a data type
+func DataTypeName(datatype int) string {
+	return adifTypeNames[datatype]
+}
+
+// Compare dotted version strings numerically
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
 		}
 	}
-	return false
+	return 0
+}
+
+func addField(name string, datatype int, enumeration string, introduced string) {
+	if _, ok := ADIFfieldInfo[name]; ok {
+		panic(fmt.Sprintf("Duplicate field name %s.", name))
+	}
+	ADIFfieldOrder = append(ADIFfieldOrder, name)
+	ADIFfieldInfo[name] = FieldInfo{
+		Name:        name,
+		DataType:    datatype,
+		Enumeration: enumeration,
+		Introduced:  introduced,
+	}
+}
+
+func setImportOnly(name string, deprecated string) {
+	info := ADIFfieldInfo[name]
+	info.ImportOnly = true
+	info.Deprecated = deprecated
+	ADIFfieldInfo[name] = info
+}
+
+func isStandardADIFField(name string) bool {
+	_, ok := ADIFfieldInfo[name]
+	return ok
 }
 
 func init() {
-	ADIFfieldInfo = make(map[string]fieldMetadata)
+	ADIFfieldInfo = make(map[string]FieldInfo)
 
 	// Common fields first
-	addField("call", ADIFString)
-	addField("station_callsign", ADIFString)
-	addField("band", ADIFString)
-	addField("freq", ADIFNumber)
-	addField("mode", ADIFString)
-	addField("qso_date", ADIFDate)
-	addField("qso_date_off", ADIFDate)
-	addField("time_on", ADIFTime)
-	addField("time_off", ADIFTime)
+	addField("call", ADIFString, "", "2.0.0")
+	addField("station_callsign", ADIFString, "", "2.0.0")
+	addField("band", ADIFEnumeration, "Band", "2.0.0")
+	addField("freq", ADIFNumber, "", "2.0.0")
+	addField("mode", ADIFEnumeration, "Mode", "2.0.0")
+	addField("submode", ADIFEnumeration, "Submode", "3.0.0")
+	addField("qso_date", ADIFDate, "", "2.0.0")
+	addField("qso_date_off", ADIFDate, "", "2.0.0")
+	addField("time_on", ADIFTime, "", "2.0.0")
+	addField("time_off", ADIFTime, "", "2.0.0")
 	// Other fields alphabetically
-	addField("address", ADIFString)
-	addField("age", ADIFNumber)
-	addField("a_index", ADIFNumber)
-	addField("ant_az", ADIFNumber)
-	addField("ant_el", ADIFNumber)
-	addField("ant_path", ADIFString)
-	addField("arrl_sect", ADIFString)
-	addField("band_rx", ADIFString)
-	addField("check", ADIFString)
-	addField("class", ADIFString)
-	addField("cnty", ADIFString)
-	addField("comment", ADIFString)
-	addField("cont", ADIFString)
-	addField("contacted_op", ADIFString)
-	addField("contest_id", ADIFString)
-	addField("country", ADIFString)
-	addField("cqz", ADIFString)
-	addField("credit_submitted", ADIFString)
-	addField("credit_granted", ADIFString)
-	addField("distance", ADIFNumber)
-	addField("dxcc", ADIFString)
-	addField("email", ADIFString)
-	addField("eq_call", ADIFString)
-	addField("eqsl_qslrdate", ADIFString)
-	addField("eqsl_qslsdate", ADIFString)
-	addField("eqsl_qsl_rcvd", ADIFString)
-	addField("eqsl_qsl_sent", ADIFString)
-	addField("force_init", ADIFBoolean)
-	addField("freq_rx", ADIFNumber)
-	addField("gridsquare", ADIFString)
-	addField("guest_op", ADIFString)
-	addField("iota", ADIFString)
-	addField("iota_island_id", ADIFString)
-	addField("ituz", ADIFNumber)
-	addField("k_index", ADIFNumber)
-	addField("lat", ADIFLocation)
-	addField("lon", ADIFLocation)
-	addField("lotw_qslrdate", ADIFDate)
-	addField("lotw_qslsdate", ADIFDate)
-	addField("lotw_qsl_rcvd", ADIFString)
-	addField("lotw_qsl_sent", ADIFString)
-	addField("max_bursts", ADIFNumber)
-	addField("ms_shower", ADIFString)
-	addField("my_city", ADIFString)
-	addField("my_cnty", ADIFString)
-	addField("my_country", ADIFString)
-	addField("my_cq_zone", ADIFNumber)
-	addField("my_gridsquare", ADIFString)
-	addField("my_iota", ADIFString)
-	addField("my_iota_island_id", ADIFString)
-	addField("my_itu_zone", ADIFNumber)
-	addField("my_lat", ADIFLocation)
-	addField("my_lon", ADIFLocation)
-	addField("my_name", ADIFString)
-	addField("my_postal_code", ADIFString)
-	addField("my_rig", ADIFString)
-	addField("my_sig", ADIFString)
-	addField("my_sig_info", ADIFString)
-	addField("my_state", ADIFString)
-	addField("my_street", ADIFString)
-	addField("name", ADIFString)
-	addField("notes", ADIFString)
-	addField("nr_bursts", ADIFNumber)
-	addField("nr_pings", ADIFNumber)
-	addField("operator", ADIFString)
-	addField("owner_callsign", ADIFString)
-	addField("pfx", ADIFString)
-	addField("precedence", ADIFString)
-	addField("prop_mode", ADIFString)
-	addField("public_key", ADIFString)
-	addField("qslmsg", ADIFString)
-	addField("qslrdate", ADIFDate)
-	addField("qslsdate", ADIFDate)
-	addField("qsl_rcvd", ADIFString)
-	addField("qsl_rcvd_via", ADIFString)
-	addField("qsl_sent", ADIFString)
-	addField("qsl_sent_via", ADIFString)
-	addField("qsl_via", ADIFString)
-	addField("qso_complete", ADIFString)
-	addField("qso_random", ADIFBoolean)
-	addField("qth", ADIFString)
-	addField("rig", ADIFString)
-	addField("rst_rcvd", ADIFString)
-	addField("rst_sent", ADIFString)
-	addField("rx_pwr", ADIFNumber)
-	addField("sat_mode", ADIFString)
-	addField("sat_name", ADIFString)
-	addField("sfi", ADIFNumber)
-	addField("sig", ADIFString)
-	addField("sig_info", ADIFString)
-	addField("srx", ADIFNumber)
-	addField("srx_string", ADIFString)
-	addField("state", ADIFString)
-	addField("stx", ADIFNumber)
-	addField("stx_string", ADIFString)
-	addField("swl", ADIFBoolean)
-	addField("ten_ten", ADIFNumber)
-	addField("tx_pwr", ADIFNumber)
-	addField("ve_prov", ADIFString)
-	addField("web", ADIFString)
+	addField("address", ADIFMultilineString, "", "2.0.0")
+	addField("address_intl", ADIFIntlMultilineString, "", "3.0.0")
+	addField("age", ADIFNumber, "", "2.0.0")
+	addField("altitude", ADIFNumber, "", "3.1.3")
+	addField("a_index", ADIFNumber, "", "2.0.0")
+	addField("ant_az", ADIFNumber, "", "2.0.0")
+	addField("ant_el", ADIFNumber, "", "2.0.0")
+	addField("ant_path", ADIFEnumeration, "Ant Path", "2.0.0")
+	addField("arrl_sect", ADIFEnumeration, "ARRL Section", "2.0.0")
+	addField("award_submitted", ADIFSponsoredAwardList, "", "3.0.0")
+	addField("award_granted", ADIFSponsoredAwardList, "", "3.0.0")
+	addField("band_rx", ADIFEnumeration, "Band", "2.0.0")
+	addField("check", ADIFString, "", "2.0.0")
+	addField("class", ADIFString, "", "2.0.0")
+	addField("clublog_qso_upload_date", ADIFDate, "", "3.0.4")
+	addField("clublog_qso_upload_status", ADIFEnumeration, "QSO Upload Status", "3.0.4")
+	addField("cnty", ADIFEnumeration, "Secondary Administrative Subdivision", "2.0.0")
+	addField("comment", ADIFString, "", "2.0.0")
+	addField("comment_intl", ADIFIntlString, "", "3.0.0")
+	addField("cont", ADIFEnumeration, "Continent", "2.0.0")
+	addField("contacted_op", ADIFString, "", "2.0.0")
+	addField("contest_id", ADIFString, "Contest ID", "2.0.0")
+	addField("country", ADIFString, "", "2.0.0")
+	addField("country_intl", ADIFIntlString, "", "3.0.0")
+	addField("cqz", ADIFPositiveInteger, "", "2.0.0")
+	addField("credit_submitted", ADIFCreditList, "", "2.0.0")
+	addField("credit_granted", ADIFCreditList, "", "2.0.0")
+	addField("darc_dok", ADIFEnumeration, "DARC DOK", "3.1.0")
+	addField("dcl_qslrdate", ADIFDate, "", "3.1.4")
+	addField("dcl_qslsdate", ADIFDate, "", "3.1.4")
+	addField("dcl_qsl_rcvd", ADIFEnumeration, "QSL Rcvd", "3.1.4")
+	addField("dcl_qsl_sent", ADIFEnumeration, "QSL Sent", "3.1.4")
+	addField("distance", ADIFNumber, "", "2.0.0")
+	addField("dxcc", ADIFEnumeration, "DXCC Entity Code", "2.0.0")
+	addField("email", ADIFString, "", "2.0.0")
+	addField("eq_call", ADIFString, "", "2.0.0")
+	addField("eqsl_ag", ADIFBoolean, "", "3.1.4")
+	addField("eqsl_qslrdate", ADIFDate, "", "2.0.0")
+	addField("eqsl_qslsdate", ADIFDate, "", "2.0.0")
+	addField("eqsl_qsl_rcvd", ADIFEnumeration, "QSL Rcvd", "2.0.0")
+	addField("eqsl_qsl_sent", ADIFEnumeration, "QSL Sent", "2.0.0")
+	addField("fists", ADIFPositiveInteger, "", "3.0.0")
+	addField("fists_cc", ADIFPositiveInteger, "", "3.0.0")
+	addField("force_init", ADIFBoolean, "", "2.0.0")
+	addField("freq_rx", ADIFNumber, "", "2.0.0")
+	addField("gridsquare", ADIFGridSquare, "", "2.0.0")
+	addField("gridsquare_ext", ADIFGridSquareExt, "", "3.1.4")
+	addField("guest_op", ADIFString, "", "2.0.0")
+	addField("hamlogeu_qso_upload_date", ADIFDate, "", "3.1.3")
+	addField("hamlogeu_qso_upload_status", ADIFEnumeration, "QSO Upload Status", "3.1.3")
+	addField("hamqth_qso_upload_date", ADIFDate, "", "3.1.3")
+	addField("hamqth_qso_upload_status", ADIFEnumeration, "QSO Upload Status", "3.1.3")
+	addField("hrdlog_qso_upload_date", ADIFDate, "", "3.0.5")
+	addField("hrdlog_qso_upload_status", ADIFEnumeration, "QSO Upload Status", "3.0.5")
+	addField("iota", ADIFIOTARefNo, "", "2.0.0")
+	addField("iota_island_id", ADIFPositiveInteger, "", "2.0.0")
+	addField("ituz", ADIFPositiveInteger, "", "2.0.0")
+	addField("k_index", ADIFInteger, "", "2.0.0")
+	addField("lat", ADIFLocation, "", "2.0.0")
+	addField("lon", ADIFLocation, "", "2.0.0")
+	addField("lotw_qslrdate", ADIFDate, "", "2.0.0")
+	addField("lotw_qslsdate", ADIFDate, "", "2.0.0")
+	addField("lotw_qsl_rcvd", ADIFEnumeration, "QSL Rcvd", "2.0.0")
+	addField("lotw_qsl_sent", ADIFEnumeration, "QSL Sent", "2.0.0")
+	addField("max_bursts", ADIFNumber, "", "2.0.0")
+	addField("ms_shower", ADIFString, "", "2.0.0")
+	addField("my_altitude", ADIFNumber, "", "3.1.3")
+	addField("my_antenna", ADIFString, "", "3.0.4")
+	addField("my_antenna_intl", ADIFIntlString, "", "3.0.4")
+	addField("my_arrl_sect", ADIFEnumeration, "ARRL Section", "3.1.3")
+	addField("my_city", ADIFString, "", "2.0.0")
+	addField("my_city_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_cnty", ADIFEnumeration, "Secondary Administrative Subdivision", "2.0.0")
+	addField("my_country", ADIFString, "", "2.0.0")
+	addField("my_country_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_cq_zone", ADIFPositiveInteger, "", "2.0.0")
+	addField("my_darc_dok", ADIFEnumeration, "DARC DOK", "3.1.0")
+	addField("my_dxcc", ADIFEnumeration, "DXCC Entity Code", "3.0.0")
+	addField("my_fists", ADIFPositiveInteger, "", "3.0.0")
+	addField("my_gridsquare", ADIFGridSquare, "", "2.0.0")
+	addField("my_gridsquare_ext", ADIFGridSquareExt, "", "3.1.4")
+	addField("my_iota", ADIFIOTARefNo, "", "2.0.0")
+	addField("my_iota_island_id", ADIFPositiveInteger, "", "2.0.0")
+	addField("my_itu_zone", ADIFPositiveInteger, "", "2.0.0")
+	addField("my_lat", ADIFLocation, "", "2.0.0")
+	addField("my_lon", ADIFLocation, "", "2.0.0")
+	addField("my_name", ADIFString, "", "2.0.0")
+	addField("my_name_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_postal_code", ADIFString, "", "2.0.0")
+	addField("my_postal_code_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_pota_ref", ADIFPOTARefList, "", "3.1.4")
+	addField("my_rig", ADIFString, "", "2.0.0")
+	addField("my_rig_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_sig", ADIFString, "", "2.0.0")
+	addField("my_sig_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_sig_info", ADIFString, "", "2.0.0")
+	addField("my_sig_info_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_sota_ref", ADIFSOTARef, "", "3.0.0")
+	addField("my_state", ADIFEnumeration, "Primary Administrative Subdivision", "2.0.0")
+	addField("my_street", ADIFString, "", "2.0.0")
+	addField("my_street_intl", ADIFIntlString, "", "3.0.0")
+	addField("my_usaca_counties", ADIFSecondarySubdivisionList, "", "3.0.0")
+	addField("my_vucc_grids", ADIFGridSquareList, "", "3.0.0")
+	addField("my_wwff_ref", ADIFWWFFRef, "", "3.1.3")
+	addField("name", ADIFString, "", "2.0.0")
+	addField("name_intl", ADIFIntlString, "", "3.0.0")
+	addField("notes", ADIFMultilineString, "", "2.0.0")
+	addField("notes_intl", ADIFIntlMultilineString, "", "3.0.0")
+	addField("nr_bursts", ADIFInteger, "", "2.0.0")
+	addField("nr_pings", ADIFInteger, "", "2.0.0")
+	addField("operator", ADIFString, "", "2.0.0")
+	addField("owner_callsign", ADIFString, "", "2.0.0")
+	addField("pfx", ADIFString, "", "2.0.0")
+	addField("pota_ref", ADIFPOTARefList, "", "3.1.4")
+	addField("precedence", ADIFString, "", "2.0.0")
+	addField("prop_mode", ADIFEnumeration, "Propagation Mode", "2.0.0")
+	addField("public_key", ADIFString, "", "2.0.0")
+	addField("qrzcom_qso_upload_date", ADIFDate, "", "3.0.5")
+	addField("qrzcom_qso_upload_status", ADIFEnumeration, "QSO Upload Status", "3.0.5")
+	addField("qslmsg", ADIFMultilineString, "", "2.0.0")
+	addField("qslmsg_intl", ADIFIntlMultilineString, "", "3.0.0")
+	addField("qslrdate", ADIFDate, "", "2.0.0")
+	addField("qslsdate", ADIFDate, "", "2.0.0")
+	addField("qsl_rcvd", ADIFEnumeration, "QSL Rcvd", "2.0.0")
+	addField("qsl_rcvd_via", ADIFEnumeration, "QSL Via", "2.0.0")
+	addField("qsl_sent", ADIFEnumeration, "QSL Sent", "2.0.0")
+	addField("qsl_sent_via", ADIFEnumeration, "QSL Via", "2.0.0")
+	addField("qsl_via", ADIFString, "", "2.0.0")
+	addField("qso_complete", ADIFEnumeration, "QSO Complete", "2.0.0")
+	addField("qso_random", ADIFBoolean, "", "2.0.0")
+	addField("qth", ADIFString, "", "2.0.0")
+	addField("qth_intl", ADIFIntlString, "", "3.0.0")
+	addField("region", ADIFEnumeration, "Region", "3.0.0")
+	addField("rig", ADIFMultilineString, "", "2.0.0")
+	addField("rig_intl", ADIFIntlMultilineString, "", "3.0.0")
+	addField("rst_rcvd", ADIFString, "", "2.0.0")
+	addField("rst_sent", ADIFString, "", "2.0.0")
+	addField("rx_pwr", ADIFNumber, "", "2.0.0")
+	addField("sat_mode", ADIFString, "", "2.0.0")
+	addField("sat_name", ADIFString, "", "2.0.0")
+	addField("sfi", ADIFInteger, "", "2.0.0")
+	addField("sig", ADIFString, "", "2.0.0")
+	addField("sig_intl", ADIFIntlString, "", "3.0.0")
+	addField("sig_info", ADIFString, "", "2.0.0")
+	addField("sig_info_intl", ADIFIntlString, "", "3.0.0")
+	addField("silent_key", ADIFBoolean, "", "3.0.5")
+	addField("skcc", ADIFString, "", "3.0.0")
+	addField("sota_ref", ADIFSOTARef, "", "3.0.0")
+	addField("srx", ADIFInteger, "", "2.0.0")
+	addField("srx_string", ADIFString, "", "2.0.0")
+	addField("state", ADIFEnumeration, "Primary Administrative Subdivision", "2.0.0")
+	addField("stx", ADIFInteger, "", "2.0.0")
+	addField("stx_string", ADIFString, "", "2.0.0")
+	addField("swl", ADIFBoolean, "", "2.0.0")
+	addField("ten_ten", ADIFPositiveInteger, "", "2.0.0")
+	addField("tx_pwr", ADIFNumber, "", "2.0.0")
+	addField("uksmg", ADIFPositiveInteger, "", "3.0.0")
+	addField("usaca_counties", ADIFSecondarySubdivisionList, "", "3.0.0")
+	addField("ve_prov", ADIFString, "", "2.0.0")
+	addField("vucc_grids", ADIFGridSquareList, "", "3.0.0")
+	addField("web", ADIFString, "", "2.0.0")
+	addField("wwff_ref", ADIFWWFFRef, "", "3.1.3")
+
+	// Fields replaced by newer ones, accepted on import only
+	setImportOnly("guest_op", "3.0.0")
+	setImportOnly("ve_prov", "3.0.0")
 }
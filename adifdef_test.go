@@ -0,0 +1,78 @@
+package adifparser
+
+import (
+	"testing"
+)
+
+func TestLookupField(t *testing.T) {
+	info, ok := LookupField("POTA_REF")
+	if !ok {
+		t.Fatal("pota_ref not found")
+	}
+	if info.DataType != ADIFPOTARefList || info.Introduced != "3.1.4" {
+		t.Fatalf("Unexpected definition %+v", info)
+	}
+	if info, _ := LookupField("DCL_QSL_SENT"); info.DataType != ADIFEnumeration || info.Enumeration != "QSL Sent" {
+		t.Fatalf("Unexpected definition %+v", info)
+	}
+	if info, _ := LookupField("dcl_qslsdate"); info.DataType != ADIFDate {
+		t.Fatalf("Unexpected definition %+v", info)
+	}
+	if info, _ := LookupField("band"); info.Enumeration != "Band" {
+		t.Fatalf("Unexpected definition %+v", info)
+	}
+	if info, _ := LookupField("name_intl"); !info.IsIntl() {
+		t.Fatalf("Expected name_intl to be an Intl field")
+	}
+	if info, _ := LookupField("ve_prov"); !info.ImportOnly {
+		t.Fatalf("Expected ve_prov to be import-only")
+	}
+	if _, ok := LookupField("app_lotw_modegroup"); ok {
+		t.Fatal("Application-defined field found")
+	}
+}
+
+func TestFieldsForVersion(t *testing.T) {
+	has := func(fields []FieldInfo, name string) bool {
+		for _, f := range fields {
+			if f.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	v2 := FieldsForVersion("2.2.7")
+	v3 := FieldsForVersion("3.1.4")
+	if !has(v2, "call") || !has(v3, "call") {
+		t.Fatal("call missing")
+	}
+	if has(v2, "submode") || !has(v3, "submode") {
+		t.Fatal("submode only exists in ADIF 3")
+	}
+	if !has(v2, "ve_prov") || has(v3, "ve_prov") {
+		t.Fatal("ve_prov is deprecated in ADIF 3")
+	}
+	v313 := FieldsForVersion("3.1.3")
+	for _, name := range []string{"gridsquare_ext", "dcl_qslrdate", "dcl_qsl_rcvd", "eqsl_ag"} {
+		if has(v313, name) || !has(v3, name) {
+			t.Fatalf("%s was introduced in 3.1.4", name)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		cmp  int
+	}{
+		{"3.1.4", "3.1.4", 0},
+		{"3.1.10", "3.1.4", 1},
+		{"2.2.7", "3.0.0", -1},
+		{"3.1", "3.1.0", 0},
+	}
+	for _, c := range cases {
+		if got := compareVersions(c.a, c.b); got != c.cmp {
+			t.Fatalf("compareVersions(%s, %s) = %d, expected %d", c.a, c.b, got, c.cmp)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package adifparser
+
+import (
+	"strings"
+)
+
+// Frequency range of an amateur band, in MHz
+type bandRange struct {
+	lower float64
+	upper float64
+}
+
+// Band enumeration
+var adifBands = map[string]bandRange{
+	"2190m":  {0.1357, 0.1378},
+	"630m":   {0.472, 0.479},
+	"560m":   {0.501, 0.504},
+	"160m":   {1.8, 2.0},
+	"80m":    {3.5, 4.0},
+	"60m":    {5.06, 5.45},
+	"40m":    {7.0, 7.3},
+	"30m":    {10.1, 10.15},
+	"20m":    {14.0, 14.35},
+	"17m":    {18.068, 18.168},
+	"15m":    {21.0, 21.45},
+	"12m":    {24.890, 24.99},
+	"10m":    {28.0, 29.7},
+	"8m":     {40, 45},
+	"6m":     {50, 54},
+	"5m":     {54.000001, 69.9},
+	"4m":     {70, 71},
+	"2m":     {144, 148},
+	"1.25m":  {222, 225},
+	"70cm":   {420, 450},
+	"33cm":   {902, 928},
+	"23cm":   {1240, 1300},
+	"13cm":   {2300, 2450},
+	"9cm":    {3300, 3500},
+	"6cm":    {5650, 5925},
+	"3cm":    {10000, 10500},
+	"1.25cm": {24000, 24250},
+	"6mm":    {47000, 47200},
+	"4mm":    {75500, 81000},
+	"2.5mm":  {119980, 123000},
+	"2mm":    {134000, 149000},
+	"1mm":    {241000, 250000},
+	"submm":  {300000, 7500000},
+}
+
+// Mode enumeration, with the submodes of each mode
+var adifModes = map[string][]string{
+	"AM":           nil,
+	"ARDOP":        nil,
+	"ATV":          nil,
+	"CHIP":         {"CHIP64", "CHIP128"},
+	"CLO":          nil,
+	"CONTESTI":     nil,
+	"CW":           {"PCW"},
+	"DIGITALVOICE": {"C4FM", "DMR", "DSTAR", "FREEDV", "M17"},
+	"DOMINO": {"DOM-M", "DOM4", "DOM5", "DOM8", "DOM11", "DOM16", "DOM22",
+		"DOM44", "DOM88", "DOMINOEX", "DOMINOF"},
+	"DYNAMIC": {"VARA HF", "VARA SATELLITE", "VARA FM 1200", "VARA FM 9600"},
+	"FAX":     nil,
+	"FM":      nil,
+	"FSK441":  nil,
+	"FT8":     nil,
+	"HELL": {"FMHELL", "FSKHELL", "HELL80", "HELLX5", "HELLX9", "HFSK",
+		"PSKHELL", "SLOWHELL"},
+	"ISCAT": {"ISCAT-A", "ISCAT-B"},
+	"JT4":   {"JT4A", "JT4B", "JT4C", "JT4D", "JT4E", "JT4F", "JT4G"},
+	"JT6M":  nil,
+	"JT9": {"JT9-1", "JT9-2", "JT9-5", "JT9-10", "JT9-30", "JT9A", "JT9B",
+		"JT9C", "JT9D", "JT9E", "JT9E FAST", "JT9F", "JT9F FAST", "JT9G",
+		"JT9G FAST", "JT9H", "JT9H FAST"},
+	"JT44": nil,
+	"JT65": {"JT65A", "JT65B", "JT65B2", "JT65C", "JT65C2"},
+	"MFSK": {"FSQCALL", "FST4", "FST4W", "FT4", "JS8", "JTMS", "MFSK4",
+		"MFSK8", "MFSK11", "MFSK16", "MFSK22", "MFSK31", "MFSK32", "MFSK64",
+		"MFSK64L", "MFSK128", "MFSK128L", "Q65"},
+	"MSK144": nil,
+	"MT63":   nil,
+	"OLIVIA": {"OLIVIA 4/125", "OLIVIA 4/250", "OLIVIA 8/250", "OLIVIA 8/500",
+		"OLIVIA 16/500", "OLIVIA 16/1000", "OLIVIA 32/1000"},
+	"OPERA": {"OPERA-BEACON", "OPERA-QSO"},
+	"PAC":   {"PAC2", "PAC3", "PAC4"},
+	"PAX":   {"PAX2"},
+	"PKT":   nil,
+	"PSK": {"8PSK125", "8PSK125F", "8PSK125FL", "8PSK250", "8PSK250F",
+		"8PSK250FL", "8PSK500", "8PSK500F", "8PSK1000", "8PSK1000F",
+		"8PSK1200F", "FSK31", "PSK10", "PSK31", "PSK63", "PSK63F",
+		"PSK63RC4", "PSK63RC5", "PSK63RC10", "PSK63RC20", "PSK63RC32",
+		"PSK125", "PSK125C12", "PSK125R", "PSK125RC10", "PSK125RC12",
+		"PSK125RC16", "PSK125RC4", "PSK125RC5", "PSK250", "PSK250C6",
+		"PSK250R", "PSK250RC2", "PSK250RC3", "PSK250RC5", "PSK250RC6",
+		"PSK250RC7", "PSK500", "PSK500C2", "PSK500C4", "PSK500R", "PSK500RC2",
+		"PSK500RC3", "PSK500RC4", "PSK800C2", "PSK800RC2", "PSK1000",
+		"PSK1000C2", "PSK1000R", "PSK1000RC2", "PSKAM10", "PSKAM31",
+		"PSKAM50", "PSKFEC31", "QPSK31", "QPSK63", "QPSK125", "QPSK250",
+		"QPSK500", "SIM31"},
+	"PSK2K": nil,
+	"Q15":   nil,
+	"QRA64": {"QRA64A", "QRA64B", "QRA64C", "QRA64D", "QRA64E"},
+	"ROS":   {"ROS-EME", "ROS-HF", "ROS-MF"},
+	"RTTY":  {"ASCI"},
+	"RTTYM": nil,
+	"SSB":   {"LSB", "USB"},
+	"SSTV":  nil,
+	"T10":   nil,
+	"THOR": {"THOR-M", "THOR4", "THOR5", "THOR8", "THOR11", "THOR16",
+		"THOR22", "THOR25X4", "THOR50X1", "THOR50X2", "THOR100"},
+	"THRB":   {"THRBX", "THRBX1", "THRBX2", "THRBX4", "THROB1", "THROB2", "THROB4"},
+	"TOR":    {"AMTORFEC", "GTOR", "NAVTEX", "SITORB"},
+	"V4":     nil,
+	"VOI":    nil,
+	"WINMOR": nil,
+	"WSPR":   nil,
+}
+
+// Modes from earlier ADIF versions that are now submodes, accepted on
+// import only
+var adifImportOnlyModes = map[string]bool{
+	"AMTORFEC": true, "ASCI": true, "C4FM": true, "CHIP64": true,
+	"CHIP128": true, "DOMINOF": true, "DSTAR": true, "FMHELL": true,
+	"FSK31": true, "GTOR": true, "HELL80": true, "HFSK": true, "JT4A": true,
+	"JT4B": true, "JT4C": true, "JT4D": true, "JT4E": true, "JT4F": true,
+	"JT4G": true, "JT65A": true, "JT65B": true, "JT65C": true, "MFSK8": true,
+	"MFSK16": true, "PAC2": true, "PAC3": true, "PAX2": true, "PCW": true,
+	"PSK10": true, "PSK31": true, "PSK63": true, "PSK63F": true,
+	"PSK125": true, "PSKAM10": true, "PSKAM31": true, "PSKAM50": true,
+	"PSKFEC31": true, "PSKHELL": true, "QPSK31": true, "QPSK63": true,
+	"QPSK125": true, "THRBX": true,
+}
+
+// Enumerations with a fixed set of values, by enumeration name.  Values
+// marked false are accepted on import only.
+var adifEnumerations = map[string]map[string]bool{
+	"Ant Path": {"G": true, "O": true, "S": true, "L": true},
+	"Continent": {"NA": true, "SA": true, "EU": true, "AF": true, "OC": true,
+		"AS": true, "AN": true},
+	"Propagation Mode": {"AS": true, "AUE": true, "AUR": true, "BS": true,
+		"ECH": true, "EME": true, "ES": true, "F2": true, "FAI": true,
+		"GWAVE": true, "INTERNET": true, "ION": true, "IRL": true, "LOS": true,
+		"MS": true, "RPT": true, "RS": true, "SAT": true, "TEP": true, "TR": true},
+	"QSL Rcvd":          {"Y": true, "N": true, "R": true, "I": true, "V": false},
+	"QSL Sent":          {"Y": true, "N": true, "R": true, "Q": true, "I": true},
+	"QSL Via":           {"B": true, "D": true, "E": true, "M": false},
+	"QSO Complete":      {"Y": true, "N": true, "NIL": true, "?": true},
+	"QSO Upload Status": {"Y": true, "N": true, "M": true},
+	"Region": {"NONE": true, "AI": true, "SY": true, "BI": true, "SI": true,
+		"KO": true, "ET": true, "IV": true},
+}
+
+// Find the mode a submode belongs to
+func submodeParent(submode string) (string, bool) {
+	submode = strings.ToUpper(submode)
+	for mode, submodes := range adifModes {
+		for _, s := range submodes {
+			if s == submode {
+				return mode, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,134 @@
+package adifparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Parsed ADIF file header
+type ADIFHeader struct {
+	// Free text preceding the first header field
+	Preamble string
+	// ADIF version (adif_ver)
+	Version string
+	// Name of the program that created the file (programid)
+	ProgramID string
+	// Version of the program that created the file (programversion)
+	ProgramVersion string
+	// Creation time as written in the file (created_timestamp)
+	CreatedTimestamp string
+	// User-defined field declarations (userdefN)
+	UserDefs []UserDef
+	// Application-defined fields (app_*), keyed by lowercase field name
+	AppFields map[string]string
+	// Names of the application-defined fields as read, by lowercase name,
+	// if they are not lowercase
+	appNames map[string]string
+	// Free text between the header fields
+	comments []Comment
+	// Text of the header, in lossless mode
+	source *headerSource
+}
+
+// A user-defined field declared in the header
+type UserDef struct {
+	// Field id, the N in USERDEFN
+	ID int
+	// Name of the user-defined field
+	Name string
+	// ADIF data type indicator (set to uppercase)
+	TypeCode byte
+	// Enumeration or range specification, e.g. "{S,M,L}" or "{5:20}"
+	Spec string
+}
+
+func newADIFHeader() *ADIFHeader {
+	header := &ADIFHeader{}
+	header.AppFields = make(map[string]string)
+	return header
+}
+
+// Get an application-defined field from the header
+func (h *ADIFHeader) GetAppField(name string) (string, error) {
+	if v, ok := h.AppFields[strings.ToLower(name)]; ok {
+		return v, nil
+	}
+	return "", NoSuchField
+}
+
+// Value of the USERDEFn header field: the name, followed by the
+// enumeration or range specification if there is one
+func (def UserDef) value() string {
+	if def.Spec == "" {
+		return def.Name
+	}
+	return def.Name + "," + def.Spec
+}
+
+// Store a header element in the appropriate place
+func (h *ADIFHeader) setElement(element *elementData) {
+	switch {
+	case element.name == "adif_ver":
+		h.Version = element.value
+	case element.name == "programid":
+		h.ProgramID = element.value
+	case element.name == "programversion":
+		h.ProgramVersion = element.value
+	case element.name == "created_timestamp":
+		h.CreatedTimestamp = element.value
+	case strings.HasPrefix(element.name, "userdef"):
+		if def, err := parseUserDef(element); err == nil {
+			h.UserDefs = append(h.UserDefs, def)
+		} else {
+			adiflog.Printf("parseUserDef: %v", err)
+		}
+	case strings.HasPrefix(element.name, "app_"):
+		cased := element.name
+		if element.tagName != "" {
+			cased = element.tagName
+		}
+		h.setAppField(cased, element.value)
+	}
+}
+
+// Store an application-defined field, keeping the case of its name for
+// output
+func (h *ADIFHeader) setAppField(cased, value string) {
+	name := strings.ToLower(cased)
+	h.AppFields[name] = value
+	if cased != name {
+		if h.appNames == nil {
+			h.appNames = make(map[string]string)
+		}
+		h.appNames[name] = cased
+	}
+}
+
+// Name of an application-defined field as it is written
+func (h *ADIFHeader) appName(name string) string {
+	if cased, ok := h.appNames[name]; ok {
+		return cased
+	}
+	return name
+}
+
+// Parse a USERDEFn header element, e.g. <USERDEF2:19:E>SweaterSize,{S,M,L}
+func parseUserDef(element *elementData) (UserDef, error) {
+	def := UserDef{}
+	id, err := strconv.Atoi(strings.TrimPrefix(element.name, "userdef"))
+	if err != nil {
+		return def, InvalidField
+	}
+	def.ID = id
+	def.TypeCode = element.typecode
+	def.Name = element.value
+	if i := strings.IndexByte(element.value, ','); i != -1 {
+		def.Name = element.value[:i]
+		def.Spec = strings.TrimSpace(element.value[i+1:])
+	}
+	def.Name = strings.TrimSpace(def.Name)
+	if def.Name == "" {
+		return def, InvalidField
+	}
+	return def, nil
+}
@@ -2,33 +2,121 @@ package adifparser
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
-	"strconv"
+	"iter"
+	"strings"
+	"unicode/utf8"
 )
 
 // Interface for ADIFReader
 type ADIFReader interface {
 	ReadRecord() (ADIFRecord, error)
+	// Read a record, giving up with ctx.Err() when ctx is done.  The
+	// input position is undefined after a cancelled read.
+	ReadRecordContext(ctx context.Context) (ADIFRecord, error)
+	// Iterate over the remaining records.  A read error is yielded with a
+	// nil record and ends the iteration.
+	All() iter.Seq2[ADIFRecord, error]
+	// Stream the remaining records on a channel, which is closed at the end
+	// of the input, after an error, or when ctx is done.
+	Records(ctx context.Context) <-chan RecordResult
 	RecordCount() int
+	// Get the parsed file header (empty if the file has none)
+	Header() *ADIFHeader
+	// Byte offset in the input of the most recently read record
+	RecordOffset() int64
+	// Length in bytes of the most recently read record
+	RecordLength() int64
 }
 
 // Real implementation of ADIFReader
 type baseADIFReader struct {
 	// Underlying bufio Reader
 	rdr *bufio.Reader
+	// Count of bytes read from the source into rdr
+	src *countingReader
+	// Cancellable source
+	ctxrdr *contextReader
 	// Whether or not the header is included
 	noHeader bool
 	// Whether or not the header has been read
 	headerRead bool
 	// Version string of the adif file
 	version string
+	// Parsed header
+	header *ADIFHeader
+	// USERDEF declarations of the header, by lowercase name
+	userDefs map[string]UserDef
 	// Record count
 	records int
+	// Offset and length of the most recent record
+	recordOffset int64
+	recordLength int64
+	// Current line number and the offset where it starts
+	line      int
+	lineStart int64
+	// Options
+	config readerConfig
+	// Count of records skipped in recovery mode
+	skipped int
+	// Raw bytes of the current record or header, kept in recovery and
+	// lossless modes, and the offset where they start
+	capture      []byte
+	captureStart int64
+	// Whether bytes are being kept in capture
+	capturing bool
+	// Text after the last record, in lossless mode
+	trailer string
+	// Text between tags not yet made into a comment
+	text []byte
+	// Most recently read byte
+	lastByte byte
+	// Element returned by readElement, reused for each element
+	element elementData
+	// Interned lowercase field names
+	names map[string]string
+	// Names of the tags at each position of the previous record, and the
+	// position of the next tag in the current one
+	recent    []internedName
+	nameIndex int
+	// Application-defined field names of the current record in the case
+	// they were read, and the table of the previous record, which is
+	// shared with the next record if it has the same names
+	appNames     []internedName
+	lastAppNames map[string]string
+	// Only find record boundaries, without keeping values
+	skim bool
+	// Number of fields in the previous record
+	lastFields int
+	// Reused buffers for field names, tags and values
+	scratch []byte
+	tag     []byte
+	value   []byte
+}
+
+// A field name in lowercase and as written in a tag
+type internedName struct {
+	name, cased string
+}
+
+// Reader that counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
 }
 
 type dedupeADIFReader struct {
-	baseADIFReader
+	ADIFReader
 	// Store seen entities
 	seen map[string]bool
 }
@@ -36,6 +124,9 @@ type dedupeADIFReader struct {
 type elementData struct {
 	// ADIF field name (in ASCII, set to lowercase)
 	name string
+	// Name as written in the tag, for application-defined fields whose
+	// name is not lowercase
+	tagName string
 	// ADIF field (if nil, only the field name exists)
 	value string
 	// ADIF data type indicator (optional, set to uppercase)
@@ -48,46 +139,224 @@ type elementData struct {
 	hasType bool
 	// Length of value bytes/string
 	valueLength int
+	// Byte offset of the opening "<"
+	offset int64
 }
 
 func (ardr *baseADIFReader) ReadRecord() (ADIFRecord, error) {
-	record := NewADIFRecord()
+	return ardr.ReadRecordContext(context.Background())
+}
+
+func (ardr *baseADIFReader) All() iter.Seq2[ADIFRecord, error] {
+	return readerSeq(ardr)
+}
+
+func (ardr *baseADIFReader) Records(ctx context.Context) <-chan RecordResult {
+	return readerChan(ctx, ardr)
+}
+
+func (ardr *baseADIFReader) ReadRecordContext(ctx context.Context) (ADIFRecord, error) {
+	ardr.ctxrdr.ctx = ctx
+	defer func() { ardr.ctxrdr.ctx = context.Background() }()
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := ardr.readRecord()
+		if err == nil || err == io.EOF {
+			return record, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var perr *ParseError
+		if !ardr.config.recover || !errors.As(err, &perr) {
+			adiflog.Printf("readElement: %v", err)
+			return nil, err
+		}
+		eof := ardr.resync()
+		ardr.text = ardr.text[:0]
+		ardr.skipped++
+		if ardr.config.onSkip != nil {
+			raw := append([]byte(nil), ardr.capture...)
+			ardr.config.onSkip(SkippedRecord{Raw: raw, Err: perr})
+		}
+		if eof {
+			return nil, io.EOF
+		}
+	}
+}
+
+// Skip input up to and including the next <eor>, returning whether the
+// end of the input was reached instead
+func (ardr *baseADIFReader) resync() bool {
+	const eor = "<eor>"
+	matched := 0
+	// The tag that failed may have ended at the start of <eor>
+	if ardr.lastByte == '<' {
+		matched = 1
+	}
+	for matched < len(eor) {
+		c, err := ardr.readByte()
+		if err != nil {
+			return true
+		}
+		if charToLower(c) == eor[matched] {
+			matched++
+		} else if c == '<' {
+			matched = 1
+		} else {
+			matched = 0
+		}
+	}
+	return false
+}
+
+func (ardr *baseADIFReader) readRecord() (ADIFRecord, error) {
+	record := newADIFRecordSize(ardr.lastFields)
 
 	if !ardr.headerRead {
-		ardr.readHeader()
+		if err := ardr.readHeader(); err != nil {
+			return nil, err
+		}
 	}
+	record.userDefs = ardr.userDefs
+	ardr.startCapture()
+	ardr.nameIndex = 0
+	ardr.appNames = ardr.appNames[:0]
+	// Fields as read, in lossless mode
+	var fields []sourceField
+	keepSource := ardr.config.lossless && !ardr.skim
 
 	foundeor := false
+	first := true
+	// Field the next comment follows
+	prev := ""
 	for !foundeor {
 		element, err := ardr.readElement()
 		if err != nil {
-			if err != io.EOF {
-				adiflog.Printf("readElement: %v", err)
+			if err == io.EOF && ardr.config.lossless {
+				ardr.trailer = string(ardr.capture)
 			}
 			return nil, err
 		}
+		if first {
+			ardr.recordOffset = element.offset
+			first = false
+		}
+		if ardr.config.comments && !ardr.skim {
+			record.comments = ardr.takeComment(record.comments, prev)
+		}
 		if element.name == "eor" && !element.hasValue {
 			foundeor = true
 			break
 		}
-		if element.hasValue {
-			// TODO: accomodate types
-			record.values[element.name] = element.value
+		if element.hasValue && keepSource {
+			start := element.offset - ardr.captureStart
+			fields = append(fields, sourceField{
+				name:     element.name,
+				value:    element.value,
+				typecode: element.typecode,
+				hasType:  element.hasType,
+				start:    int(start),
+				end:      int(ardr.position() - ardr.captureStart),
+			})
+		}
+		if element.hasValue && !ardr.skim {
+			prev = element.name
+			record.set(element.name, element.value)
+			if element.hasType {
+				record.setType(element.name, element.typecode)
+			}
+			if element.tagName != "" {
+				ardr.appNames = append(ardr.appNames, internedName{element.name, element.tagName})
+			} else if strings.HasPrefix(element.name, "app_") && len(ardr.appNames) > 0 {
+				ardr.appNames = append(ardr.appNames, internedName{element.name, element.name})
+			}
 		}
 	}
 	// Successfully parsed the record
+	ardr.setAppNames(record)
 	ardr.records++
+	ardr.recordLength = ardr.position() - ardr.recordOffset
+	if ardr.config.lossless {
+		eorStart := int(ardr.element.offset - ardr.captureStart)
+		ardr.readTrailingText()
+		if keepSource {
+			record.source = newRecordSource(ardr.capture,
+				int(ardr.recordOffset-ardr.captureStart), eorStart, fields, ardr.sourceOptions())
+		}
+	}
+	ardr.lastFields = len(record.values)
 	return record, nil
 }
 
+// Give a record the case of its application-defined field names
+func (ardr *baseADIFReader) setAppNames(record *baseADIFRecord) {
+	if len(ardr.appNames) == 0 {
+		return
+	}
+	same := true
+	cased := 0
+	for i, n := range ardr.appNames {
+		if !isLastName(ardr.appNames, i) || n.cased == n.name {
+			continue
+		}
+		cased++
+		same = same && ardr.lastAppNames[n.name] == n.cased
+	}
+	if !same || cased != len(ardr.lastAppNames) {
+		for _, n := range ardr.appNames {
+			record.setAppName(n.name, n.cased)
+		}
+		ardr.lastAppNames = record.appNames
+	}
+	record.appNames = ardr.lastAppNames
+	record.sharedAppNames = true
+}
+
+// Whether a name is not repeated later in the list
+func isLastName(names []internedName, i int) bool {
+	for _, n := range names[i+1:] {
+		if n.name == names[i].name {
+			return false
+		}
+	}
+	return true
+}
+
 // Errors
 var InvalidFieldLength = errors.New("Invalid field length.")
 var TypeCodeExceedOneByte = errors.New("Type Code exceeds one byte.")
 var UnknownColons = errors.New("Unknown colons in the tag.")
+var TagTooLong = errors.New("Tag is too long.")
+
+const (
+	// Largest field length accepted
+	maxFieldLength = 1<<31 - 1
+	// Values up to this length are read into a reused buffer
+	maxBufferedValue = 1 << 16
+	// Size limit of the field name table
+	maxInternedNames = 4096
+	// Number of tag positions whose names are remembered
+	maxRecentNames = 256
+)
 
 func (ardr *dedupeADIFReader) ReadRecord() (ADIFRecord, error) {
+	return ardr.ReadRecordContext(context.Background())
+}
+
+func (ardr *dedupeADIFReader) All() iter.Seq2[ADIFRecord, error] {
+	return readerSeq(ardr)
+}
+
+func (ardr *dedupeADIFReader) Records(ctx context.Context) <-chan RecordResult {
+	return readerChan(ctx, ardr)
+}
+
+func (ardr *dedupeADIFReader) ReadRecordContext(ctx context.Context) (ADIFRecord, error) {
 	for true {
-		record, err := ardr.baseADIFReader.ReadRecord()
+		record, err := ardr.ADIFReader.ReadRecordContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -100,24 +369,34 @@ func (ardr *dedupeADIFReader) ReadRecord() (ADIFRecord, error) {
 	return nil, nil
 }
 
-func NewADIFReader(r io.Reader) *baseADIFReader {
+func NewADIFReader(r io.Reader, opts ...ReaderOption) *baseADIFReader {
 	reader := &baseADIFReader{}
+	reader.config = newReaderConfig(opts)
 	reader.init(r)
 	return reader
 }
 
-func NewDedupeADIFReader(r io.Reader) *dedupeADIFReader {
+func NewDedupeADIFReader(r io.Reader, opts ...ReaderOption) *dedupeADIFReader {
+	return NewDedupeReader(NewADIFReader(r, opts...))
+}
+
+// Wrap any ADIFReader, skipping duplicate records
+func NewDedupeReader(r ADIFReader) *dedupeADIFReader {
 	reader := &dedupeADIFReader{}
-	reader.init(r)
+	reader.ADIFReader = r
 	reader.seen = make(map[string]bool)
 	return reader
 }
 
 func (ardr *baseADIFReader) init(r io.Reader) {
-	ardr.rdr = bufio.NewReader(r)
+	ardr.ctxrdr = newContextReader(r)
+	ardr.src = &countingReader{r: ardr.ctxrdr}
+	ardr.rdr = bufio.NewReader(ardr.src)
 	// Assumption
 	ardr.version = "2.0"
+	ardr.header = newADIFHeader()
 	ardr.records = 0
+	ardr.line = 1
 	// check header
 	filestart, err := ardr.rdr.Peek(1)
 	if err != nil {
@@ -128,141 +407,593 @@ func (ardr *baseADIFReader) init(r io.Reader) {
 	// if header does not exist, header can be skipped
 	// and treated as read
 	ardr.headerRead = ardr.noHeader
+	if ardr.noHeader && ardr.config.lossless {
+		ardr.header.source = newHeaderSource(ardr.header, "", ardr.sourceOptions())
+	}
+}
+
+// Options the text kept in lossless mode is read with
+func (ardr *baseADIFReader) sourceOptions() sourceOptions {
+	return sourceOptions{ardr.config.charset, ardr.config.lengthMode}
+}
+
+// Start keeping the bytes read, if the options need them
+func (ardr *baseADIFReader) startCapture() {
+	ardr.capturing = ardr.config.recover || ardr.config.lossless
+	if ardr.capturing {
+		ardr.capture = ardr.capture[:0]
+		ardr.captureStart = ardr.position()
+	}
+}
+
+// Read the text after a record or header, up to the next tag, so that it
+// is kept with it
+func (ardr *baseADIFReader) readTrailingText() {
+	for {
+		buf, _ := ardr.peekBuffered()
+		if len(buf) == 0 {
+			return
+		}
+		if i := bytes.IndexByte(buf, '<'); i != -1 {
+			ardr.keepText(buf[:i])
+			ardr.consume(i)
+			return
+		}
+		ardr.keepText(buf)
+		ardr.consume(len(buf))
+	}
+}
+
+// Keep text between tags, if comments are kept
+func (ardr *baseADIFReader) keepText(text []byte) {
+	if ardr.config.comments && !ardr.skim {
+		ardr.text = append(ardr.text, text...)
+	}
+}
+
+// Add the text kept since the last call as a comment following a field
+func (ardr *baseADIFReader) takeComment(comments []Comment, field string) []Comment {
+	text := bytes.TrimSpace(ardr.text)
+	ardr.text = ardr.text[:0]
+	if len(text) == 0 {
+		return comments
+	}
+	return append(comments, Comment{Field: field, Text: ardr.config.charset.decode(text)})
 }
 
-func (ardr *baseADIFReader) readHeader() {
+// Read the header.  If the read is cancelled, the header is put back in
+// front of the input and the context's error is returned, so that it is
+// read again from the start.
+func (ardr *baseADIFReader) readHeader() error {
+	if ardr.header == nil {
+		ardr.header = newADIFHeader()
+	}
+	// The header is kept even if the options don't need it, to read it
+	// again after a cancelled read
+	ardr.capturing = true
+	ardr.capture = ardr.capture[:0]
+	ardr.captureStart = ardr.position()
+	line, lineStart := ardr.line, ardr.lineStart
+	// Free text up to the first field is the preamble
+	var preamble strings.Builder
+	inPreamble := true
 	foundeoh := false
+	prev := ""
 	for !foundeoh {
+		if inPreamble {
+			text, err := ardr.readText()
+			preamble.WriteString(text)
+			if err != nil {
+				if cerr := ardr.contextErr(); cerr != nil {
+					ardr.unreadHeader(line, lineStart)
+					return cerr
+				}
+				// TODO: Log the error somewhere
+				break
+			}
+		}
 		element, err := ardr.readElement()
 		if err != nil {
+			if cerr := ardr.contextErr(); cerr != nil {
+				ardr.unreadHeader(line, lineStart)
+				return cerr
+			}
 			// TODO: Log the error somewhere
-			return
+			break
+		}
+		if ardr.config.comments {
+			ardr.header.comments = ardr.takeComment(ardr.header.comments, prev)
 		}
 		if element.name == "eoh" && !element.hasValue {
 			foundeoh = true
 			break
 		}
-		if element.name == "adif_ver" && element.hasValue {
+		if !element.hasValue {
+			// Not a field, e.g. an e-mail address in the preamble
+			if inPreamble {
+				fmt.Fprintf(&preamble, "<%s>", element.name)
+			}
+			continue
+		}
+		inPreamble = false
+		prev = element.name
+		if element.name == "adif_ver" {
 			ardr.version = element.value
 		}
+		ardr.header.setElement(element)
 	}
 
+	ardr.header.Preamble = ardr.config.charset.decode(
+		[]byte(strings.TrimSpace(preamble.String())))
 	ardr.headerRead = true
+	ardr.userDefs = userDefMap(ardr.header.UserDefs)
+	if foundeoh && ardr.config.lossless {
+		ardr.readTrailingText()
+		ardr.header.source = newHeaderSource(ardr.header, string(ardr.capture), ardr.sourceOptions())
+	}
+	ardr.capturing = false
+	return nil
+}
+
+// Error of the current read's context, if it is done
+func (ardr *baseADIFReader) contextErr() error {
+	if ardr.ctxrdr == nil {
+		return nil
+	}
+	return ardr.ctxrdr.ctx.Err()
+}
+
+// Put the bytes of a partly read header back in front of the input, and
+// go back to the position where the header started
+func (ardr *baseADIFReader) unreadHeader(line int, lineStart int64) {
+	read := append([]byte(nil), ardr.capture...)
+	ardr.src = &countingReader{
+		r: io.MultiReader(bytes.NewReader(read), ardr.rdr),
+		n: ardr.captureStart,
+	}
+	ardr.rdr = bufio.NewReaderSize(ardr.src, ardr.rdr.Size())
+	ardr.line, ardr.lineStart = line, lineStart
+	ardr.header = newADIFHeader()
+	ardr.version = "2.0"
+	ardr.capturing = false
+	ardr.capture = ardr.capture[:0]
+	ardr.text = ardr.text[:0]
+}
+
+// Get the file header, reading it if necessary
+func (ardr *baseADIFReader) Header() *ADIFHeader {
+	if !ardr.headerRead {
+		ardr.readHeader()
+	}
+	if ardr.header == nil {
+		ardr.header = newADIFHeader()
+	}
+	return ardr.header
+}
+
+// Read free text up to (but not including) the next "<"
+func (ardr *baseADIFReader) readText() (string, error) {
+	start := ardr.position()
+	text, err := ardr.rdr.ReadString('<')
+	if err == nil {
+		ardr.rdr.UnreadByte()
+		text = text[:len(text)-1]
+	}
+	ardr.account([]byte(text), start)
+	return text, err
+}
+
+// Text after the last complete record, such as an end of file marker,
+// kept in lossless mode once ReadRecord has returned io.EOF
+func (ardr *baseADIFReader) Trailer() string {
+	return ardr.trailer
 }
 
 func (ardr *baseADIFReader) RecordCount() int {
 	return ardr.records
 }
 
+func (ardr *baseADIFReader) RecordOffset() int64 {
+	return ardr.recordOffset
+}
+
+func (ardr *baseADIFReader) RecordLength() int64 {
+	return ardr.recordLength
+}
+
+// Current byte offset in the input
+func (ardr *baseADIFReader) position() int64 {
+	if ardr.src == nil {
+		return 0
+	}
+	return ardr.src.n - int64(ardr.rdr.Buffered())
+}
+
 func (ardr *baseADIFReader) readElement() (*elementData, error) {
-	var c byte
-	var err error
-	var fieldname []byte
-	var fieldvalue []byte
-	var fieldtype byte
-	var fieldlenstr []byte
-	var fieldlength int = 0
-
-	data := &elementData{}
-	data.name = ""
-	data.value = ""
-	data.typecode = 0
-	data.valueLength = 0
-
-	// Look for "<" (open tag) first
-	foundopentag := false
-	for !foundopentag {
-		// Read a byte (aka character)
-		c, err = ardr.rdr.ReadByte()
-		if err != nil {
+	data := &ardr.element
+	*data = elementData{}
+
+	// Skip to the "<" (open tag)
+	for {
+		buf, err := ardr.peekBuffered()
+		if len(buf) == 0 {
 			return nil, err
 		}
-		foundopentag = c == '<'
+		if i := bytes.IndexByte(buf, '<'); i != -1 {
+			ardr.keepText(buf[:i])
+			ardr.consume(i + 1)
+			break
+		}
+		ardr.keepText(buf)
+		ardr.consume(len(buf))
 	}
+	data.offset = ardr.position() - 1
+	line, column := ardr.line, int(data.offset-ardr.lineStart)+1
 
-	// Get field name
-	data.hasValue = false
-	data.hasType = false
-	// Look for ">" (close tag) next
-	foundclosetag := false
-	foundcolonnum := 0
-	foundtype := false
-	for !foundclosetag {
-		// Read a byte (aka character)
-		c, err = ardr.rdr.ReadByte()
+	// Build a ParseError for the raw tag text read so far
+	tagError := func(err error, raw string) error {
+		record := 0
+		if ardr.headerRead {
+			record = ardr.records + ardr.skipped + 1
+		}
+		return &ParseError{
+			Offset: data.offset,
+			Line:   line,
+			Column: column,
+			Record: record,
+			Tag:    "<" + raw,
+			Err:    err,
+		}
+	}
+
+	// Find the ">" (close tag)
+	buf, end, err := ardr.peekTag()
+	tag := buf
+	if end != -1 {
+		tag = buf[:end]
+	}
+
+	// Split the tag into name, length and type
+	parts, bad, perr := splitTag(tag)
+	if perr != nil {
+		raw := string(tag[:bad+1])
+		ardr.consume(bad + 1)
+		return nil, tagError(perr, raw)
+	}
+	colons, nameEnd, lengthEnd, length := parts.colons, parts.nameEnd, parts.lengthEnd, parts.length
+	if end == -1 {
+		raw := string(tag)
+		ardr.consume(len(tag))
+		if err == nil {
+			err = TagTooLong
+		} else {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, tagError(err, raw)
+	}
+
+	name, cased := ardr.internName(tag[:nameEnd])
+	data.name = name
+	data.tagName = ""
+	if cased != name && strings.HasPrefix(name, "app_") {
+		data.tagName = cased
+	}
+	data.hasValue = colons > 0
+	data.hasType = colons > 1
+	if data.hasType && lengthEnd+1 < len(tag) {
+		data.typecode = charToUpper(tag[lengthEnd+1])
+	}
+	if data.hasValue && lengthEnd == nameEnd+1 {
+		// No digits in the length
+		raw := string(buf[:end+1])
+		ardr.consume(end + 1)
+		return nil, tagError(InvalidFieldLength, raw)
+	}
+	// Keep the tag for errors reading the value
+	ardr.tag = append(ardr.tag[:0], tag...)
+	ardr.consume(end + 1)
+
+	// Get field value/content,
+	// with the length specified by the field length
+	if data.hasValue {
+		data.valueLength = length
+		fieldvalue, err := ardr.readValue(length)
 		if err != nil {
-			return nil, err
+			return nil, tagError(io.ErrUnexpectedEOF, string(ardr.tag))
 		}
-		foundclosetag = c == '>'
-		if foundclosetag {
-			break
+		if !ardr.skim {
+			data.value = ardr.config.charset.decode(fieldvalue)
 		}
-		switch foundcolonnum {
-		case 0:
-			// no colon yet: append the byte to the field name
-			if c == ':' {
-				foundcolonnum++
-				data.hasValue = true
-			} else {
-				fieldname = append(fieldname, c)
-			}
-			break
-		case 1:
-			// 1 colon found:
-			// handle the byte as a digit in the length
-			if c == ':' {
-				foundcolonnum++
-				data.hasType = true
-			} else {
-				if c >= '0' && c <= '9' {
-					fieldlenstr = append(fieldlenstr, c)
-				} else {
-					return nil, InvalidFieldLength
-				}
+	}
+
+	return data, nil
+}
+
+// Positions of the colons in a tag and the length it gives
+type tagParts struct {
+	colons             int
+	nameEnd, lengthEnd int
+	length             int
+}
+
+// Split the text of a tag into name, length and type, returning the index
+// of the offending byte with an error
+func splitTag(tag []byte) (tagParts, int, error) {
+	parts := tagParts{nameEnd: len(tag), lengthEnd: len(tag)}
+	for i, c := range tag {
+		var err error
+		switch {
+		case parts.colons == 0 && c == ':':
+			parts.nameEnd = i
+			parts.colons++
+		case parts.colons == 0:
+		case parts.colons == 1 && c == ':':
+			parts.lengthEnd = i
+			parts.colons++
+		case parts.colons == 1 && c >= '0' && c <= '9':
+			if parts.length > (maxFieldLength-9)/10 {
+				err = InvalidFieldLength
 			}
-			break
-		case 2:
-			// 2 colons found:
-			// pick up only one byte and use it as a field type
-			if !foundtype {
-				fieldtype = c
-				foundtype = true
-			} else {
-				return nil, TypeCodeExceedOneByte
+			parts.length = parts.length*10 + int(c-'0')
+		case parts.colons == 1:
+			err = InvalidFieldLength
+		case i > parts.lengthEnd+1:
+			err = TypeCodeExceedOneByte
+		}
+		if err != nil {
+			return parts, i, err
+		}
+	}
+	return parts, 0, nil
+}
+
+// Return the buffered input, filling the buffer if it is empty
+func (ardr *baseADIFReader) peekBuffered() ([]byte, error) {
+	if ardr.rdr.Buffered() == 0 {
+		if _, err := ardr.rdr.Peek(1); err != nil {
+			return nil, err
+		}
+	}
+	return ardr.rdr.Peek(ardr.rdr.Buffered())
+}
+
+// Peek at the input up to the next ">", returning the buffered input and
+// the position of the ">", or -1 with an error if it can't be found
+func (ardr *baseADIFReader) peekTag() ([]byte, int, error) {
+	n := ardr.rdr.Buffered()
+	for {
+		if n == 0 {
+			n = 1
+		}
+		buf, err := ardr.rdr.Peek(n)
+		if end := bytes.IndexByte(buf, '>'); end != -1 {
+			return buf, end, nil
+		}
+		if err != nil {
+			return buf, -1, err
+		}
+		if len(buf) == ardr.rdr.Size() {
+			return buf, -1, nil
+		}
+		// Wait for more input
+		n = len(buf) + 1
+		if b := ardr.rdr.Buffered(); b > n {
+			n = b
+		}
+	}
+}
+
+// Consume n buffered bytes
+func (ardr *baseADIFReader) consume(n int) {
+	if n == 0 {
+		return
+	}
+	buf, _ := ardr.rdr.Peek(n)
+	ardr.account(buf, ardr.position())
+	ardr.rdr.Discard(n)
+}
+
+// Keep track of line numbers, captured bytes and the last byte for input
+// starting at offset start
+func (ardr *baseADIFReader) account(buf []byte, start int64) {
+	if len(buf) == 0 {
+		return
+	}
+	if i := bytes.LastIndexByte(buf, '\n'); i != -1 {
+		ardr.line += bytes.Count(buf, []byte{'\n'})
+		ardr.lineStart = start + int64(i) + 1
+	}
+	if ardr.capturing {
+		ardr.capture = append(ardr.capture, buf...)
+	}
+	ardr.lastByte = buf[len(buf)-1]
+}
+
+// Lowercase a field name, returning shared copies of it in lowercase and
+// as written.  Records usually have the same fields in the same order, so
+// the name at the same position of the previous record is tried first.
+func (ardr *baseADIFReader) internName(raw []byte) (string, string) {
+	i := ardr.nameIndex
+	ardr.nameIndex++
+	if i < len(ardr.recent) && ardr.recent[i].cased == string(raw) {
+		return ardr.recent[i].name, ardr.recent[i].cased
+	}
+	name := ardr.lookupName(raw)
+	cased := name
+	if string(raw) != name {
+		cased = string(raw)
+	}
+	entry := internedName{name: name, cased: cased}
+	if i < len(ardr.recent) {
+		ardr.recent[i] = entry
+	} else if i < maxRecentNames {
+		ardr.recent = append(ardr.recent, entry)
+	}
+	return name, cased
+}
+
+// Find the shared lowercase copy of a name in the name table
+func (ardr *baseADIFReader) lookupName(name []byte) string {
+	for _, c := range name {
+		if 'A' <= c && c <= 'Z' {
+			ardr.scratch = append(ardr.scratch[:0], name...)
+			for i, c := range ardr.scratch {
+				ardr.scratch[i] = charToLower(c)
 			}
+			name = ardr.scratch
 			break
-			// This code should not be reached...
-		default:
-			return nil, UnknownColons
 		}
 	}
+	if s, ok := ardr.names[string(name)]; ok {
+		return s
+	}
+	s := string(name)
+	if ardr.names == nil {
+		ardr.names = make(map[string]string)
+	}
+	// Don't let garbage in the input grow the table without limit
+	if len(ardr.names) < maxInternedNames {
+		ardr.names[s] = s
+	}
+	return s
+}
 
-	// Make the field name lowercase
-	data.name = string(bStrictToLower(fieldname))
-	// Make the field type name uppercase
-	if foundtype {
-		data.typecode = charToUpper(fieldtype)
+// Read length bytes into a buffer that is reused by the next call
+func (ardr *baseADIFReader) readBytes(length int) ([]byte, error) {
+	start := ardr.position()
+	if length > maxBufferedValue {
+		// Don't trust the length for the allocation
+		var value bytes.Buffer
+		n, err := io.CopyN(&value, ardr.rdr, int64(length))
+		ardr.account(value.Bytes()[:n], start)
+		return value.Bytes(), err
 	}
+	if cap(ardr.value) < length {
+		ardr.value = make([]byte, length)
+	}
+	value := ardr.value[:length]
+	n, err := io.ReadFull(ardr.rdr, value)
+	ardr.account(value[:n], start)
+	return value, err
+}
 
-	// Get field length
-	if data.hasValue {
-		fieldlength, err = strconv.Atoi(string(fieldlenstr))
+// Read a field value of the given length, interpreted according to the
+// length mode
+func (ardr *baseADIFReader) readValue(length int) ([]byte, error) {
+	if ardr.config.lengthMode == LengthRunes {
+		return ardr.readRunes(length)
+	}
+	value, err := ardr.readBytes(length)
+	if err != nil {
+		return nil, err
+	}
+	if ardr.config.lengthMode == LengthAuto && !isASCIIBytes(value) {
+		return ardr.extendToRunes(value, length)
+	}
+	return value, nil
+}
+
+// Whether a byte slice is 7-bit ASCII
+func isASCIIBytes(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Read length UTF-8 characters.  Invalid bytes count as one character
+// each.
+func (ardr *baseADIFReader) readRunes(length int) ([]byte, error) {
+	// Don't trust the length for the allocation
+	value := make([]byte, 0, min(length, maxBufferedValue))
+	for n := 0; n < length; n++ {
+		c, err := ardr.readByte()
 		if err != nil {
 			return nil, err
 		}
-		data.valueLength = fieldlength
-
-		// Get field value/content,
-		// with the byte length specified by the field length
-		for i := 0; i < fieldlength; i++ {
-			c, err = ardr.rdr.ReadByte()
-			if err != nil {
-				return nil, err
+		value = append(value, c)
+		// Read the continuation bytes of a multi-byte character
+		for i := 1; i < utf8SequenceLength(c); i++ {
+			next, err := ardr.rdr.Peek(1)
+			if err != nil || utf8.RuneStart(next[0]) {
+				break
 			}
-			fieldvalue = append(fieldvalue, c)
+			cont, _ := ardr.readByte()
+			value = append(value, cont)
 		}
-		data.value = string(fieldvalue)
 	}
+	return value, nil
+}
 
-	return data, nil
+// Length of the UTF-8 sequence started by a byte
+func utf8SequenceLength(c byte) int {
+	switch {
+	case c&0xE0 == 0xC0:
+		return 2
+	case c&0xF0 == 0xE0:
+		return 3
+	case c&0xF8 == 0xF0:
+		return 4
+	}
+	return 1
+}
+
+// Decide whether a value that was read as length bytes was really length
+// characters, by checking which interpretation is followed by a tag
+func (ardr *baseADIFReader) extendToRunes(value []byte, length int) ([]byte, error) {
+	ahead, err := ardr.rdr.Peek(ardr.rdr.Size())
+	atEOF := err == io.EOF
+	if followedByTag(ahead, 0, atEOF) {
+		return value, nil
+	}
+	// Find how many more bytes hold the remaining characters
+	combined := append(append([]byte(nil), value...), ahead...)
+	end := 0
+	for n := 0; n < length && end < len(combined); n++ {
+		_, size := utf8.DecodeRune(combined[end:])
+		end += size
+	}
+	if end <= len(value) || !followedByTag(combined, end, atEOF) {
+		return value, nil
+	}
+	for i := len(value); i < end; i++ {
+		c, _ := ardr.readByte()
+		value = append(value, c)
+	}
+	return value, nil
+}
+
+// Whether the data from pos onwards is whitespace followed by a tag (or
+// the end of the input)
+func followedByTag(data []byte, pos int, atEOF bool) bool {
+	for ; pos < len(data); pos++ {
+		switch data[pos] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '<':
+			return true
+		default:
+			return false
+		}
+	}
+	return atEOF
+}
+
+// Read a byte, keeping track of line numbers
+func (ardr *baseADIFReader) readByte() (byte, error) {
+	c, err := ardr.rdr.ReadByte()
+	if err != nil {
+		return c, err
+	}
+	if c == '\n' {
+		ardr.line++
+		ardr.lineStart = ardr.position()
+	}
+	if ardr.capturing {
+		ardr.capture = append(ardr.capture, c)
+	}
+	ardr.lastByte = c
+	return c, nil
 }
@@ -3,11 +3,17 @@ package adifparser
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func testHeaderFile(t *testing.T, filename string) {
@@ -315,3 +321,618 @@ func TestForReadElement(t *testing.T) {
 	}
 
 }
+
+func TestHeaderFields(t *testing.T) {
+	f, err := os.Open("testdata/lotw.adi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	reader := NewADIFReader(f)
+	header := reader.Header()
+	if !strings.HasPrefix(header.Preamble, "ARRL Logbook of the World Status Report") {
+		t.Fatalf("Unexpected preamble %q", header.Preamble)
+	}
+	if !strings.HasSuffix(header.Preamble, "QSL ONLY: YES") {
+		t.Fatalf("Unexpected preamble %q", header.Preamble)
+	}
+	if header.ProgramID != "LoTW" {
+		t.Fatalf("Expected programid LoTW, got %q", header.ProgramID)
+	}
+	if v, err := header.GetAppField("APP_LoTW_LASTQSL"); err != nil {
+		t.Fatal(err)
+	} else if v != "2015-06-02 21:02:09" {
+		t.Fatalf("Unexpected app_lotw_lastqsl %q", v)
+	}
+	if v := header.AppFields["app_lotw_numrec"]; v != "250" {
+		t.Fatalf("Unexpected app_lotw_numrec %q", v)
+	}
+	// Header must still be usable after reading records
+	if _, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	if reader.Header() != header {
+		t.Fatal("Header changed after reading a record")
+	}
+}
+
+func TestHeaderUserDefs(t *testing.T) {
+	buf := strings.NewReader("Preamble <a@b.c>\n<ADIF_VER:5>3.1.4 <PROGRAMID:4>test " +
+		"<PROGRAMVERSION:3>1.0 <CREATED_TIMESTAMP:15>20240101 120000 " +
+		"<USERDEF1:3:N>EPC <USERDEF2:19:E>SweaterSize,{S,M,L}<EOH>" +
+		"<call:4>W1AW<EPC:2>12<eor>")
+	reader := NewADIFReader(buf)
+	header := reader.Header()
+	if header.Preamble != "Preamble <a@b.c>" {
+		t.Fatalf("Unexpected preamble %q", header.Preamble)
+	}
+	if header.Version != "3.1.4" || header.ProgramVersion != "1.0" ||
+		header.CreatedTimestamp != "20240101 120000" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+	expected := []UserDef{
+		{ID: 1, Name: "EPC", TypeCode: 'N'},
+		{ID: 2, Name: "SweaterSize", TypeCode: 'E', Spec: "{S,M,L}"},
+	}
+	if len(header.UserDefs) != len(expected) {
+		t.Fatalf("Expected %d userdefs, got %d", len(expected), len(header.UserDefs))
+	}
+	for i, def := range expected {
+		if header.UserDefs[i] != def {
+			t.Fatalf("Expected %+v, got %+v", def, header.UserDefs[i])
+		}
+	}
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("epc"); v != "12" {
+		t.Fatalf("Unexpected epc %q", v)
+	}
+}
+
+func TestUserDefSpecs(t *testing.T) {
+	enum := UserDef{Name: "SweaterSize", TypeCode: 'E', Spec: "{S, M,L}"}
+	if values := enum.Enumeration(); len(values) != 3 || values[1] != "M" {
+		t.Fatalf("Unexpected enumeration %q", values)
+	}
+	if _, _, ok := enum.Range(); ok {
+		t.Fatal("Enumeration read as a range")
+	}
+	rng := UserDef{Name: "ShoeSize", TypeCode: 'N', Spec: "{5:20}"}
+	if min, max, ok := rng.Range(); !ok || min != 5 || max != 20 {
+		t.Fatalf("Unexpected range %v %v %v", min, max, ok)
+	}
+	if rng.Enumeration() != nil {
+		t.Fatal("Range read as an enumeration")
+	}
+}
+
+func TestUserDefFieldTypes(t *testing.T) {
+	buf := strings.NewReader("Test\n<USERDEF1:3:N>EPC <USERDEF2:19:E>SweaterSize,{S,M,L}<EOH>" +
+		"<call:4>W1AW<EPC:2>12<sweatersize:1>M<eor>")
+	reader := NewADIFReader(buf)
+	if def, ok := reader.Header().GetUserDef("epc"); !ok || def.ID != 1 {
+		t.Fatalf("Unexpected declaration %+v", def)
+	}
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := record.GetInt("epc"); err != nil || n != 12 {
+		t.Fatalf("Unexpected epc %v %v", n, err)
+	}
+	if _, err := record.GetBool("epc"); !errors.Is(err, TypeMismatch) {
+		t.Fatalf("Expected %v, got %v", TypeMismatch, err)
+	}
+}
+
+func TestHeaderAbsent(t *testing.T) {
+	reader := NewADIFReader(strings.NewReader("<call:4>W1AW<eor>"))
+	header := reader.Header()
+	if header == nil || header.Version != "" || header.Preamble != "" {
+		t.Fatalf("Expected empty header, got %+v", header)
+	}
+	if _, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutoReader(t *testing.T) {
+	inputs := map[string]bool{
+		"<call:4>W1AW<eor>":             false,
+		"Comment<eoh><call:4>W1AW<eor>": false,
+		"\xef\xbb\xbf<call:4>W1AW<eor>": false,
+		"<?xml version=\"1.0\"?><ADX><RECORDS><RECORD>" +
+			"<CALL>W1AW</CALL></RECORD></RECORDS></ADX>": true,
+		"\xef\xbb\xbf\n  <?xml version=\"1.0\"?>\n<ADX><RECORDS><RECORD>" +
+			"<CALL>W1AW</CALL></RECORD></RECORDS></ADX>": true,
+		" <adx><RECORDS><RECORD><CALL>W1AW</CALL></RECORD></RECORDS></adx>": true,
+	}
+	for input, isXML := range inputs {
+		reader := NewAutoReader(strings.NewReader(input))
+		if _, ok := reader.(*adxReader); ok != isXML {
+			t.Fatalf("Wrong reader type %T for %q", reader, input)
+		}
+		record, err := reader.ReadRecord()
+		if err != nil {
+			t.Fatalf("input %q: %v", input, err)
+		}
+		if v, _ := record.GetValue("call"); v != "W1AW" {
+			t.Fatalf("input %q: unexpected call %q", input, v)
+		}
+	}
+}
+
+func TestDedupeADXReader(t *testing.T) {
+	f, err := os.Open("testdata/sample.adx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader := NewDedupeReader(NewAutoReader(f))
+	count := 0
+	for _, err := reader.ReadRecord(); err != io.EOF; _, err = reader.ReadRecord() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 records, got %d", count)
+	}
+}
+
+func TestRecordOffset(t *testing.T) {
+	input := "Header<eoh>\n<call:4>W1AW<eor>\n  <call:4>K1AB<eor>"
+	reader := NewADIFReader(strings.NewReader(input))
+	for _, expected := range []int64{12, 32} {
+		if _, err := reader.ReadRecord(); err != nil {
+			t.Fatal(err)
+		}
+		if reader.RecordOffset() != expected {
+			t.Fatalf("Expected offset %d, got %d", expected, reader.RecordOffset())
+		}
+		if !strings.HasPrefix(input[expected:], "<call:4>") {
+			t.Fatalf("Offset %d does not point at the record", expected)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		input  string
+		err    error
+		line   int
+		column int
+		record int
+		tag    string
+	}{
+		{"<call:4>W1AW<eor>\n<call:4>W1AW\n  <freq:x>1<eor>",
+			InvalidFieldLength, 3, 3, 2, "<freq:x"},
+		{"Header\n<eoh>\n<call:4:SS>W1AW<eor>",
+			TypeCodeExceedOneByte, 3, 1, 1, "<call:4:SS"},
+		{"<call:>W1AW<eor>", InvalidFieldLength, 1, 1, 1, "<call:>"},
+		{"<call:4>W1AW<eor><call:10>W1AW", io.ErrUnexpectedEOF, 1, 18, 2, "<call:10"},
+	}
+	for _, c := range cases {
+		reader := NewADIFReader(strings.NewReader(c.input))
+		var err error
+		for err == nil {
+			_, err = reader.ReadRecord()
+		}
+		if !errors.Is(err, c.err) {
+			t.Fatalf("input %q: expected %v, got %v", c.input, c.err, err)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("input %q: expected ParseError, got %T", c.input, err)
+		}
+		if perr.Line != c.line || perr.Column != c.column ||
+			perr.Record != c.record || perr.Tag != c.tag {
+			t.Fatalf("input %q: unexpected error %+v", c.input, perr)
+		}
+		if c.input[perr.Offset] != '<' {
+			t.Fatalf("input %q: offset %d is not a tag", c.input, perr.Offset)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	input := "<call:4>W1AW<eor>\n<call:4>K1AB<freq:x>14<eor>\n" +
+		"<call:4>N0CA<eor>\n<call:4:XY>W2XX<eor>\n<call:4>W1AW<eor><call:2"
+	var skipped []SkippedRecord
+	reader := NewADIFReader(strings.NewReader(input), WithRecovery(func(s SkippedRecord) {
+		skipped = append(skipped, s)
+	}))
+	var calls []string
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		call, _ := record.GetValue("call")
+		calls = append(calls, call)
+	}
+	if strings.Join(calls, ",") != "W1AW,N0CA,W1AW" {
+		t.Fatalf("Unexpected records %v", calls)
+	}
+	if reader.RecordCount() != 3 {
+		t.Fatalf("Expected 3 records, got %d", reader.RecordCount())
+	}
+	if len(skipped) != 3 {
+		t.Fatalf("Expected 3 skipped records, got %d", len(skipped))
+	}
+	if string(skipped[0].Raw) != "\n<call:4>K1AB<freq:x>14<eor>" {
+		t.Fatalf("Unexpected raw record %q", skipped[0].Raw)
+	}
+	expected := []struct {
+		record int
+		err    error
+	}{{2, InvalidFieldLength}, {4, TypeCodeExceedOneByte}, {6, io.ErrUnexpectedEOF}}
+	for i, e := range expected {
+		if skipped[i].Err.Record != e.record || skipped[i].Err.Err != e.err {
+			t.Fatalf("Unexpected error %+v", skipped[i].Err)
+		}
+	}
+}
+
+func TestRecoveryAtEOR(t *testing.T) {
+	// The malformed tag runs into the <eor> of its own record
+	input := "<call:4<eor><call:4>W1AW<eor>"
+	reader := NewADIFReader(strings.NewReader(input), WithRecovery(nil))
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("call"); v != "W1AW" {
+		t.Fatalf("Unexpected call %q", v)
+	}
+}
+
+func TestStrictByDefault(t *testing.T) {
+	reader := NewADIFReader(strings.NewReader("<freq:x>14<eor><call:4>W1AW<eor>"))
+	if _, err := reader.ReadRecord(); !errors.Is(err, InvalidFieldLength) {
+		t.Fatalf("Expected %v, got %v", InvalidFieldLength, err)
+	}
+}
+
+func TestLengthModes(t *testing.T) {
+	// "Jürgen" is 6 characters but 7 bytes
+	cases := []struct {
+		input    string
+		mode     LengthMode
+		expected string
+	}{
+		{"<name_intl:7>J\xc3\xbcrgen<qth:3>ABC<eor>", LengthBytes, "J\xc3\xbcrgen"},
+		{"<name_intl:6>J\xc3\xbcrgen<qth:3>ABC<eor>", LengthRunes, "J\xc3\xbcrgen"},
+		{"<name_intl:6>J\xc3\xbcrgen <qth:3>ABC<eor>", LengthAuto, "J\xc3\xbcrgen"},
+		{"<name_intl:7>J\xc3\xbcrgen\n<qth:3>ABC<eor>", LengthAuto, "J\xc3\xbcrgen"},
+		{"<name_intl:6>\xe6\x9d\xb1\xe4\xba\xac<qth:3>ABC<eor>", LengthAuto, "\xe6\x9d\xb1\xe4\xba\xac"},
+		{"<name_intl:2>\xe6\x9d\xb1\xe4\xba\xac<qth:3>ABC<eor>", LengthAuto, "\xe6\x9d\xb1\xe4\xba\xac"},
+		{"<name_intl:2>\xe6\x9d\xb1\xe4\xba\xac<qth:3>ABC<eor>", LengthRunes, "\xe6\x9d\xb1\xe4\xba\xac"},
+		{"<name_intl:2>\xe6\x9d\xb1\xe4\xba\xac<qth:3>ABC<eor>", LengthBytes, "\xe6\x9d"},
+		// Invalid UTF-8 counts as one character per byte
+		{"<name:4>AB\xedD<qth:3>ABC<eor>", LengthRunes, "AB\xedD"},
+		{"<name:4>AB\xedD<qth:3>ABC<eor>", LengthAuto, "AB\xedD"},
+	}
+	for _, c := range cases {
+		reader := NewADIFReader(strings.NewReader(c.input), WithLengthMode(c.mode))
+		record, err := reader.ReadRecord()
+		if err != nil {
+			t.Fatalf("input %q: %v", c.input, err)
+		}
+		name, _ := record.GetValue("name_intl")
+		if name == "" {
+			name, _ = record.GetValue("name")
+		}
+		if name != c.expected {
+			t.Fatalf("input %q: expected %q, got %q", c.input, c.expected, name)
+		}
+		if qth, _ := record.GetValue("qth"); qth != "ABC" {
+			t.Fatalf("input %q: expected qth ABC, got %q", c.input, qth)
+		}
+	}
+}
+
+func TestCharsets(t *testing.T) {
+	cases := []struct {
+		input    string
+		charset  Charset
+		expected string
+	}{
+		{"<name:6>J\xfcrgen<qth:3>ABC<eor>", CharsetRaw, "J\xfcrgen"},
+		{"<name:6>J\xfcrgen<qth:3>ABC<eor>", CharsetISO88591, "Jürgen"},
+		{"<name:6>J\xfcrgen<qth:3>ABC<eor>", CharsetWindows1252, "Jürgen"},
+		{"<name:6>J\xfcrgen<qth:3>ABC<eor>", CharsetAuto, "Jürgen"},
+		{"<name:7>J\xc3\xbcrgen<qth:3>ABC<eor>", CharsetAuto, "Jürgen"},
+		{"<name:6>J\xfcrgen<qth:3>ABC<eor>", CharsetUTF8, "J�rgen"},
+		{"<name:5>\x93Bob\x94<qth:3>ABC<eor>", CharsetWindows1252, "“Bob”"},
+		{"<name:5>\x93Bob\x94<qth:3>ABC<eor>", CharsetISO88591, "\u0093Bob\u0094"},
+	}
+	for _, c := range cases {
+		reader := NewADIFReader(strings.NewReader(c.input), WithCharset(c.charset))
+		record, err := reader.ReadRecord()
+		if err != nil {
+			t.Fatalf("input %q: %v", c.input, err)
+		}
+		if name, _ := record.GetValue("name"); name != c.expected {
+			t.Fatalf("input %q: expected %q, got %q", c.input, c.expected, name)
+		}
+		if qth, _ := record.GetValue("qth"); qth != "ABC" {
+			t.Fatalf("input %q: expected qth ABC, got %q", c.input, qth)
+		}
+	}
+}
+
+func TestADXCharset(t *testing.T) {
+	input := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<ADX><HEADER></HEADER><RECORDS><RECORD><NAME>J\xfcrgen</NAME></RECORD></RECORDS></ADX>"
+	record, err := NewADXReader(strings.NewReader(input)).ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := record.GetValue("name"); name != "Jürgen" {
+		t.Fatalf("Unexpected name %q", name)
+	}
+}
+
+func TestReadRecordContext(t *testing.T) {
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("<eoh>\n<call:4>W1AW<eor>\n"))
+	reader := NewADIFReader(pr)
+	if _, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing more is written, so this blocks until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := reader.ReadRecordContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// No part of a record had been read when the read was cancelled, so
+	// the next call reads the whole record
+	go func() {
+		pw.Write([]byte("<call:5>KF4MD<eor>\n"))
+		pw.Close()
+	}()
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call, _ := record.GetValue("call"); call != "KF4MD" {
+		t.Fatalf("Unexpected call %q", call)
+	}
+
+	cancel()
+	if _, err := NewADXReader(strings.NewReader("<ADX></ADX>")).ReadRecordContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestReadHeaderContext(t *testing.T) {
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("Preamble\n<adif_ver:5>3.1.4\n<progr"))
+	reader := NewADIFReader(pr)
+
+	// The header is cut off, so this blocks until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := reader.ReadRecordContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// The header is read again from the start
+	go func() {
+		pw.Write([]byte("amid:4>test<eoh>\n<call:4>W1AW<eor>\n"))
+		pw.Close()
+	}()
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := record.GetFields(); len(fields) != 1 || fields[0] != "call" {
+		t.Fatalf("Unexpected fields %v", fields)
+	}
+	header := reader.Header()
+	if header.Preamble != "Preamble" || header.Version != "3.1.4" || header.ProgramID != "test" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+	if offset := reader.RecordOffset(); offset != 50 {
+		t.Fatalf("Unexpected offset %d", offset)
+	}
+}
+
+func TestAll(t *testing.T) {
+	input := "<eoh><call:4>W1AW<eor><call:5>KF4MD<eor><call:4>N0CA<eor>"
+	var calls []string
+	for record, err := range NewADIFReader(strings.NewReader(input)).All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		call, _ := record.GetValue("call")
+		calls = append(calls, call)
+	}
+	if strings.Join(calls, ",") != "W1AW,KF4MD,N0CA" {
+		t.Fatalf("Unexpected calls %v", calls)
+	}
+
+	// Early termination leaves the rest of the input unread
+	reader := NewADIFReader(strings.NewReader(input))
+	for range reader.All() {
+		break
+	}
+	if record, _ := reader.ReadRecord(); record == nil {
+		t.Fatal("Expected a record after breaking out of the loop")
+	} else if call, _ := record.GetValue("call"); call != "KF4MD" {
+		t.Fatalf("Unexpected call %q", call)
+	}
+
+	// An error ends the iteration
+	count := 0
+	for record, err := range NewADIFReader(strings.NewReader("<eoh><call:4>W1AW<eor><call:x>")).All() {
+		count++
+		if count == 2 && (record != nil || !errors.Is(err, InvalidFieldLength)) {
+			t.Fatalf("Expected %v, got %v", InvalidFieldLength, err)
+		}
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", count)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	input := "<eoh><call:4>W1AW<eor><call:5>KF4MD<eor><call:4>N0CA<eor>"
+	count := 0
+	for result := range NewADIFReader(strings.NewReader(input)).Records(context.Background()) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		count++
+	}
+	if count != 3 {
+		t.Fatalf("Expected 3 records, got %d", count)
+	}
+
+	// Cancelling closes the channel
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("<eoh><call:4>W1AW<eor>"))
+	results := NewADIFReader(pr).Records(ctx)
+	if result := <-results; result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	cancel()
+	for result := range results {
+		if result.Err != context.Canceled {
+			t.Fatalf("Expected %v, got %v", context.Canceled, result.Err)
+		}
+	}
+}
+
+// Synthetic log of n typical records
+func benchmarkInput(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("Benchmark log\n<adif_ver:5>3.1.4 <programid:10>adifparser <eoh>\n")
+	for i := 0; i < n; i++ {
+		call := fmt.Sprintf("W%dAW", i%1000)
+		fmt.Fprintf(&buf, "<CALL:%d>%s <QSO_DATE:8>20240101 <TIME_ON:6>%06d <BAND:3>20m "+
+			"<MODE:3>FT8 <FREQ:9:N>14.074123 <RST_SENT:3>-10 <RST_RCVD:3>-12 "+
+			"<GRIDSQUARE:4>FN31 <COMMENT:15>benchmark entry <APP_LoTW_QSLMODE:3>FT8 <EOR>\n",
+			len(call), call, i%240000)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkReadRecord(b *testing.B) {
+	const records = 1000
+	input := benchmarkInput(records)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := NewADIFReader(bytes.NewReader(input))
+		for _, err := range reader.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		if reader.RecordCount() != records {
+			b.Fatalf("Expected %d records, got %d", records, reader.RecordCount())
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N*records), "allocs/record")
+}
+
+func TestLongValuesAndTags(t *testing.T) {
+	long := strings.Repeat("x", maxBufferedValue+10)
+	input := fmt.Sprintf("<notes:%d>%s<call:4>W1AW<eor>", len(long), long)
+	record, err := NewADIFReader(strings.NewReader(input)).ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := record.GetValue("notes"); notes != long {
+		t.Fatalf("Expected %d bytes, got %d", len(long), len(notes))
+	}
+
+	// A declared length past the end of the input
+	_, err = NewADIFReader(strings.NewReader("<notes:99999999>short<eor>")).ReadRecord()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+
+	input = "<" + strings.Repeat("x", 8192) + "><call:4>W1AW<eor>"
+	_, err = NewADIFReader(strings.NewReader(input)).ReadRecord()
+	if !errors.Is(err, TagTooLong) {
+		t.Fatalf("Expected %v, got %v", TagTooLong, err)
+	}
+
+	// Counting characters doesn't allocate the declared length either
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	reader := NewADIFReader(strings.NewReader("<call:2000000000>W1AW<eor>"), WithLengthMode(LengthRunes))
+	if _, err := reader.ReadRecord(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected %v, got %v", io.ErrUnexpectedEOF, err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<24 {
+		t.Fatalf("Allocated %d bytes for a short value", allocated)
+	}
+}
+
+func TestComments(t *testing.T) {
+	f, err := os.Open("testdata/lotw.adi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader := NewADIFReader(f, WithComments())
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Comment{
+		{"call", "// LotW ADIF now includes comments like this one."},
+		{"qslrdate", "// A comment ending a record is a special case."},
+	}
+	if !reflect.DeepEqual(record.Comments(), expected) {
+		t.Fatalf("Expected %v, got %v", expected, record.Comments())
+	}
+	if len(reader.Header().Comments()) != 0 {
+		t.Fatalf("Unexpected header comments %v", reader.Header().Comments())
+	}
+	record, _ = reader.ReadRecord()
+	if comments := record.Comments(); comments != nil {
+		t.Fatalf("Unexpected comments %v", comments)
+	}
+
+	// Header comments, and text before the first field of a record
+	input := "Preamble\n<adif_ver:5>3.1.4 // version\n<eoh>\n# first\n<call:4>W1AW<eor>"
+	reader = NewADIFReader(strings.NewReader(input), WithComments())
+	record, err = reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := reader.Header().Comments(); len(c) != 1 || c[0] != (Comment{"adif_ver", "// version"}) {
+		t.Fatalf("Unexpected header comments %v", c)
+	}
+	if c := record.Comments(); len(c) != 1 || c[0] != (Comment{"", "# first"}) {
+		t.Fatalf("Unexpected comments %v", c)
+	}
+
+	// Comments are not kept by default
+	record, _ = NewADIFReader(strings.NewReader(input)).ReadRecord()
+	if c := record.Comments(); c != nil {
+		t.Fatalf("Unexpected comments %v", c)
+	}
+}
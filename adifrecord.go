@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // Public interface for ADIFRecords
@@ -20,11 +21,60 @@ type ADIFRecord interface {
 	SetValue(string, string)
 	// Get all of the present field names
 	GetFields() []string
+	// Field names in canonical (SpecOrder) order
+	Fields() []string
+	// Whether a field is present, and the number of fields
+	Has(string) bool
+	Len() int
+	// Call f for each field in canonical order, until it returns false
+	Range(f func(name, value string) bool)
+	DeleteField(string) (bool, error)
+	// Rename a field, keeping its value and type
+	RenameField(from, to string) error
+	// Deep copy of the record
+	Clone() ADIFRecord
+	// Copy the fields of another record, resolving conflicts by policy
+	Merge(other ADIFRecord, policy MergePolicy) error
+	// Typed getters, using the field's data type
+	GetFloat(string) (float64, error)
+	GetInt(string) (int, error)
+	GetBool(string) (bool, error)
+	GetDate(string) (time.Time, error)
+	GetTime(string) (time.Time, error)
+	GetLocation(string) (float64, error)
+	// QSO start and end as UTC timestamps
+	QSOStart() (time.Time, error)
+	QSOEnd() (time.Time, error)
+	SetQSOStart(time.Time)
+	SetQSOEnd(time.Time)
+	// Application-defined (APP_) fields
+	AppFields(programid string) []AppField
+	GetAppField(programid, name string) (AppField, error)
+	SetAppField(AppField) error
+	// Free text read between the fields
+	Comments() []Comment
+	SetComments([]Comment)
 }
 
 // Internal implementation for ADIFRecord
 type baseADIFRecord struct {
 	values map[string]string
+	// Explicit data type indicators, if present (set to uppercase)
+	types map[string]byte
+	// Field names in the order they were first set
+	order []string
+	// Free text between the fields
+	comments []Comment
+	// USERDEF declarations of the file the record was read from
+	userDefs map[string]UserDef
+	// Application-defined field names in the case they were read or set
+	// with, by lowercase name
+	appNames map[string]string
+	// Whether appNames is shared with other records, and must be copied
+	// before it is changed
+	sharedAppNames bool
+	// Text of the record, in lossless mode
+	source *recordSource
 }
 
 type fieldData struct {
@@ -41,28 +91,51 @@ var InvalidField = errors.New("Invalid field definition.")
 
 // Create a new ADIFRecord from scratch
 func NewADIFRecord() *baseADIFRecord {
+	return newADIFRecordSize(0)
+}
+
+// Create a record with room for size fields
+func newADIFRecordSize(size int) *baseADIFRecord {
 	record := &baseADIFRecord{}
-	record.values = make(map[string]string)
+	record.values = make(map[string]string, size)
+	record.order = make([]string, 0, size)
 	return record
 }
 
+// Set a value, keeping track of the order of fields
+func (r *baseADIFRecord) set(name, value string) {
+	if _, ok := r.values[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.values[name] = value
+}
+
+// Set the data type indicator of a field.  Most records have none, so the
+// map is only made when one is set.
+func (r *baseADIFRecord) setType(name string, typecode byte) {
+	if r.types == nil {
+		r.types = make(map[string]byte)
+	}
+	r.types[name] = typecode
+}
+
 func serializeField(name string, value string) string {
 	return fmt.Sprintf("<%s:%d>%s", name, len(value), value)
 }
 
+// Get the explicit data type indicator of a field, or 0 if there is none
+func recordTypeCode(r ADIFRecord, name string) byte {
+	if br, ok := r.(*baseADIFRecord); ok {
+		return br.types[name]
+	}
+	return 0
+}
+
 // Print an ADIFRecord as a string
 func (r *baseADIFRecord) ToString() string {
 	var record bytes.Buffer
-	for _, n := range ADIFfieldOrder {
-		if v, ok := r.values[n]; ok {
-			record.WriteString(serializeField(n, v))
-		}
-	}
-	// Handle custom fields
-	for n, v := range r.values {
-		if !isStandardADIFField(n) {
-			record.WriteString(serializeField(n, v))
-		}
+	for _, n := range sortFields(r.GetFields()) {
+		record.WriteString(serializeField(n, r.values[n]))
 	}
 	return record.String()
 }
@@ -87,7 +160,7 @@ func (r *baseADIFRecord) Fingerprint() string {
 
 // Get a value
 func (r *baseADIFRecord) GetValue(name string) (string, error) {
-	if v, ok := r.values[name]; ok {
+	if v, ok := r.values[strings.ToLower(name)]; ok {
 		return v, nil
 	}
 	return "", NoSuchField
@@ -95,7 +168,16 @@ func (r *baseADIFRecord) GetValue(name string) (string, error) {
 
 // Set a value
 func (r *baseADIFRecord) SetValue(name string, value string) {
-	r.values[strings.ToLower(name)] = value
+	name = strings.ToLower(name)
+	r.set(name, value)
+	delete(r.types, name)
+}
+
+// Set a value with an explicit data type indicator
+func (r *baseADIFRecord) SetTypedValue(name string, value string, typecode byte) {
+	name = strings.ToLower(name)
+	r.set(name, value)
+	r.setType(name, charToUpper(typecode))
 }
 
 // Get all of the present field names
@@ -109,10 +191,105 @@ func (r *baseADIFRecord) GetFields() []string {
 	return keys
 }
 
+// Get the field names in canonical order
+func (r *baseADIFRecord) Fields() []string {
+	return sortFields(r.GetFields())
+}
+
+// Whether a field is present
+func (r *baseADIFRecord) Has(name string) bool {
+	_, ok := r.values[strings.ToLower(name)]
+	return ok
+}
+
+// Number of fields
+func (r *baseADIFRecord) Len() int {
+	return len(r.values)
+}
+
+// Call f for each field in canonical order, until it returns false.  f
+// may change the record.
+func (r *baseADIFRecord) Range(f func(name, value string) bool) {
+	for _, name := range r.Fields() {
+		value, ok := r.values[name]
+		if ok && !f(name, value) {
+			return
+		}
+	}
+}
+
+// Copy a record, including its types, comments and source
+func (r *baseADIFRecord) Clone() ADIFRecord {
+	c := newADIFRecordSize(len(r.values))
+	for name, value := range r.values {
+		c.values[name] = value
+	}
+	for name, code := range r.types {
+		c.setType(name, code)
+	}
+	c.order = append(c.order, r.order...)
+	c.comments = append([]Comment(nil), r.comments...)
+	for name, cased := range r.appNames {
+		c.setAppName(name, cased)
+	}
+	// Declarations and sources are not changed once read
+	c.userDefs = r.userDefs
+	c.source = r.source
+	return c
+}
+
+// Rename a field, keeping its value, type and position.  A field with the
+// new name is replaced.
+func (r *baseADIFRecord) RenameField(from, to string) error {
+	from, cased := strings.ToLower(from), to
+	to = strings.ToLower(to)
+	value, ok := r.values[from]
+	if !ok {
+		return NoSuchField
+	}
+	if from == to {
+		return nil
+	}
+	code, typed := r.types[from]
+	r.DeleteField(to)
+	for i, n := range r.order {
+		if n == from {
+			r.order[i] = to
+		}
+	}
+	delete(r.values, from)
+	delete(r.types, from)
+	r.ownAppNames()
+	delete(r.appNames, from)
+	r.values[to] = value
+	if typed {
+		r.setType(to, code)
+	}
+	if strings.HasPrefix(to, "app_") {
+		r.setAppName(to, cased)
+	}
+	for i := range r.comments {
+		if r.comments[i].Field == from {
+			r.comments[i].Field = to
+		}
+	}
+	return nil
+}
+
 // Delete a field (from the internal map)
 func (r *baseADIFRecord) DeleteField(name string) (bool, error) {
+	name = strings.ToLower(name)
 	if _, ok := r.values[name]; ok {
 		delete(r.values, name)
+		delete(r.types, name)
+		r.ownAppNames()
+		delete(r.appNames, name)
+		for i, n := range r.order {
+			if n == name {
+				r.order = append(r.order[:i], r.order[i+1:]...)
+				break
+			}
+		}
 		return true, nil
 	}
 	return false, NoSuchField
@@ -1,8 +1,12 @@
 package adifparser
 
 import (
+	"errors"
+	"io"
+	"math"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetFields(t *testing.T) {
@@ -56,3 +60,322 @@ OUTER:
 		t.Fatalf("Expected field %v wasn't in the actual fields", exp)
 	}
 }
+
+func TestTypedGetters(t *testing.T) {
+	buf := strings.NewReader("<freq:9>14.076492<qso_date:8>20150523<time_on:4>0247" +
+		"<lat:11>S033 52.100<lon:11>E151 12.600<swl:1>y<ituz:2>06" +
+		"<mycount:2:N>42<mydate:8:D>20200101<eor>")
+	reader := NewADIFReader(buf)
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f, err := record.GetFloat("freq"); err != nil {
+		t.Fatal(err)
+	} else if f != 14.076492 {
+		t.Fatalf("Expected 14.076492, got %v", f)
+	}
+	if i, err := record.GetInt("ituz"); err != nil {
+		t.Fatal(err)
+	} else if i != 6 {
+		t.Fatalf("Expected 6, got %v", i)
+	}
+	if i, err := record.GetInt("mycount"); err != nil {
+		t.Fatal(err)
+	} else if i != 42 {
+		t.Fatalf("Expected 42, got %v", i)
+	}
+	if b, err := record.GetBool("swl"); err != nil {
+		t.Fatal(err)
+	} else if !b {
+		t.Fatal("Expected swl to be true")
+	}
+	if d, err := record.GetDate("qso_date"); err != nil {
+		t.Fatal(err)
+	} else if d != time.Date(2015, 5, 23, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("Unexpected date %v", d)
+	}
+	if d, err := record.GetDate("mydate"); err != nil {
+		t.Fatal(err)
+	} else if d.Year() != 2020 {
+		t.Fatalf("Unexpected date %v", d)
+	}
+	if tm, err := record.GetTime("time_on"); err != nil {
+		t.Fatal(err)
+	} else if tm.Hour() != 2 || tm.Minute() != 47 || tm.Second() != 0 {
+		t.Fatalf("Unexpected time %v", tm)
+	}
+	if l, err := record.GetLocation("lat"); err != nil {
+		t.Fatal(err)
+	} else if math.Abs(l+33.868333) > 1e-6 {
+		t.Fatalf("Unexpected latitude %v", l)
+	}
+	if l, err := record.GetLocation("lon"); err != nil {
+		t.Fatal(err)
+	} else if math.Abs(l-151.21) > 1e-6 {
+		t.Fatalf("Unexpected longitude %v", l)
+	}
+}
+
+func TestTypedGetterErrors(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("freq", "14.07.6")
+	record.SetValue("qso_date", "20151323")
+	record.SetTypedValue("mycount", "12", 'd')
+
+	if _, err := record.GetFloat("freq"); !errors.Is(err, InvalidValue) {
+		t.Fatalf("Expected %v, got %v", InvalidValue, err)
+	}
+	if _, err := record.GetDate("qso_date"); !errors.Is(err, InvalidValue) {
+		t.Fatalf("Expected %v, got %v", InvalidValue, err)
+	}
+	var convErr *ConversionError
+	if _, err := record.GetFloat("qso_date"); !errors.As(err, &convErr) {
+		t.Fatalf("Expected ConversionError, got %v", err)
+	} else if convErr.Err != TypeMismatch || convErr.Field != "qso_date" {
+		t.Fatalf("Unexpected error %v", convErr)
+	}
+	if _, err := record.GetInt("mycount"); !errors.Is(err, TypeMismatch) {
+		t.Fatalf("Expected %v, got %v", TypeMismatch, err)
+	}
+	if _, err := record.GetBool("missing"); err != NoSuchField {
+		t.Fatalf("Expected %v, got %v", NoSuchField, err)
+	}
+}
+
+func TestQSOTimes(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("qso_date", "20150523")
+	record.SetValue("time_on", "2347")
+	record.SetValue("time_off", "001512")
+
+	start, err := record.QSOStart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != time.Date(2015, 5, 23, 23, 47, 0, 0, time.UTC) {
+		t.Fatalf("Unexpected start %v", start)
+	}
+	end, err := record.QSOEnd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != time.Date(2015, 5, 24, 0, 15, 12, 0, time.UTC) {
+		t.Fatalf("Unexpected end %v", end)
+	}
+
+	// An explicit qso_date_off is used as-is
+	record.SetValue("qso_date_off", "20150525")
+	if end, err = record.QSOEnd(); err != nil {
+		t.Fatal(err)
+	} else if end != time.Date(2015, 5, 25, 0, 15, 12, 0, time.UTC) {
+		t.Fatalf("Unexpected end %v", end)
+	}
+
+	loc := time.FixedZone("UTC-7", -7*3600)
+	record.SetQSOStart(time.Date(2020, 1, 1, 20, 1, 2, 0, loc))
+	record.SetQSOEnd(time.Date(2020, 1, 1, 20, 5, 0, 0, loc))
+	for field, expected := range map[string]string{
+		"qso_date": "20200102", "time_on": "030102",
+		"qso_date_off": "20200102", "time_off": "030500"} {
+		if v, _ := record.GetValue(field); v != expected {
+			t.Fatalf("Expected %s to be %s, got %s", field, expected, v)
+		}
+	}
+
+	if _, err := NewADIFRecord().QSOEnd(); err != NoSuchField {
+		t.Fatalf("Expected %v, got %v", NoSuchField, err)
+	}
+}
+
+func TestAppFields(t *testing.T) {
+	reader := NewADIFReader(strings.NewReader(
+		"<call:4>W1AW<APP_LoTW_MODEGROUP:5>PHONE<app_n1mm_points:1:N>3<eor>"))
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := record.AppFields("")
+	if len(fields) != 2 {
+		t.Fatalf("Unexpected fields %+v", fields)
+	}
+	if fields[0] != (AppField{"LoTW", "MODEGROUP", 0, "PHONE"}) {
+		t.Fatalf("Unexpected field %+v", fields[0])
+	}
+	if fields := record.AppFields("N1MM"); len(fields) != 1 || fields[0] != (AppField{"n1mm", "points", 'N', "3"}) {
+		t.Fatalf("Unexpected fields %+v", fields)
+	}
+	if field, err := record.GetAppField("lotw", "modegroup"); err != nil || field.Value != "PHONE" {
+		t.Fatalf("Unexpected field %+v %v", field, err)
+	}
+	if _, err := record.GetAppField("LoTW", "NUMREC"); err != NoSuchField {
+		t.Fatalf("Expected %v, got %v", NoSuchField, err)
+	}
+
+	if err := record.SetAppField(AppField{"MyLog", "Rating", 'N', "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("app_mylog_rating"); v != "5" {
+		t.Fatalf("Unexpected value %q", v)
+	}
+	if err := record.SetAppField(AppField{"My_Log", "Rating", 0, "5"}); err != InvalidField {
+		t.Fatalf("Expected %v, got %v", InvalidField, err)
+	}
+
+	// The program ids keep their case when written
+	var buf strings.Builder
+	writer := NewADIFWriter(&buf)
+	writer.WriteRecord(record)
+	writer.Flush()
+	expected := "<call:4>W1AW<APP_LoTW_MODEGROUP:5>PHONE<APP_MyLog_Rating:1:N>5<app_n1mm_points:1:N>3<eor>\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+	record.DeleteField("app_lotw_modegroup")
+	record.SetValue("app_lotw_modegroup", "CW")
+	if fields := record.AppFields("lotw"); len(fields) != 1 || fields[0].ProgramID != "lotw" {
+		t.Fatalf("Unexpected fields %+v", fields)
+	}
+}
+
+func TestAppFieldCasePerRecord(t *testing.T) {
+	reader := NewADIFReader(strings.NewReader(
+		"<APP_LoTW_MODE:2>CW<eor>\n" +
+			"<APP_LoTW_MODE:2>CW<eor>\n" +
+			"<APP_LoTW_MODE:2>CW<app_lotw_mode:3>FT8<eor>\n" +
+			"<APP_LOTW_MODE:2>CW<eor>\n"))
+	var records []ADIFRecord
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	// Changing one record leaves the others alone
+	records[0].SetAppField(AppField{"MyLog", "Mode", 0, "SSB"})
+	records[0].SetAppField(AppField{"lotw", "mode", 0, "SSB"})
+	for i, expected := range []string{"lotw", "LoTW", "lotw", "LOTW"} {
+		if f, err := records[i].GetAppField("lotw", "mode"); err != nil || f.ProgramID != expected {
+			t.Fatalf("Record %d: expected %q, got %+v (%v)", i, expected, f, err)
+		}
+	}
+	if f, _ := records[1].GetAppField("mylog", "mode"); f.ProgramID != "" {
+		t.Fatalf("Unexpected field %+v", f)
+	}
+}
+
+func TestRecordFields(t *testing.T) {
+	var record ADIFRecord = NewADIFRecord()
+	record.SetValue("zz_note", "x")
+	record.SetValue("time_on", "1200")
+	record.SetValue("call", "W1AW")
+	if fields := record.Fields(); strings.Join(fields, ",") != "call,time_on,zz_note" {
+		t.Fatalf("Unexpected fields %v", fields)
+	}
+	if !record.Has("CALL") || record.Has("band") || record.Len() != 3 {
+		t.Fatal("Unexpected Has or Len")
+	}
+	if v, err := record.GetValue("Call"); err != nil || v != "W1AW" {
+		t.Fatalf("Unexpected value %q (%v)", v, err)
+	}
+	var names []string
+	record.Range(func(name, value string) bool {
+		names = append(names, name+"="+value)
+		return name != "time_on"
+	})
+	if strings.Join(names, ",") != "call=W1AW,time_on=1200" {
+		t.Fatalf("Unexpected fields %v", names)
+	}
+	if ok, err := record.DeleteField("ZZ_NOTE"); !ok || err != nil || record.Len() != 2 {
+		t.Fatalf("Unexpected delete result %v %v", ok, err)
+	}
+}
+
+func TestRecordClone(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetTypedValue("shoesize", "9", 'N')
+	record.SetAppField(AppField{"MyLog", "Rating", 0, "5"})
+	record.SetComments([]Comment{{"shoesize", "# note"}})
+	clone := record.Clone()
+	clone.SetValue("shoesize", "10")
+	if v, _ := record.GetValue("shoesize"); v != "9" {
+		t.Fatalf("Clone changed the record: %q", v)
+	}
+	if n, err := clone.GetInt("app_mylog_rating"); err != nil || n != 5 {
+		t.Fatalf("Unexpected value %v %v", n, err)
+	}
+	if f, _ := clone.GetAppField("mylog", "rating"); f.ProgramID != "MyLog" {
+		t.Fatalf("Unexpected field %+v", f)
+	}
+	if c := clone.Comments(); len(c) != 1 {
+		t.Fatalf("Unexpected comments %v", c)
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+	record.SetTypedValue("shoe", "9", 'N')
+	record.SetValue("band", "20m")
+	if err := record.RenameField("shoe", "ShoeSize"); err != nil {
+		t.Fatal(err)
+	}
+	if record.Has("shoe") || recordTypeCode(record, "shoesize") != 'N' {
+		t.Fatal("Field not renamed")
+	}
+	if order := InputOrder(record); strings.Join(order, ",") != "call,shoesize,band" {
+		t.Fatalf("Unexpected order %v", order)
+	}
+	if err := record.RenameField("shoe", "x"); err != NoSuchField {
+		t.Fatalf("Expected %v, got %v", NoSuchField, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	newRecord := func() ADIFRecord {
+		record := NewADIFRecord()
+		record.SetValue("call", "W1AW")
+		record.SetValue("band", "")
+		record.SetValue("mode", "CW")
+		return record
+	}
+	other := NewADIFRecord()
+	other.SetValue("band", "20m")
+	other.SetValue("mode", "SSB")
+	other.SetTypedValue("shoesize", "9", 'N')
+
+	record := newRecord()
+	if err := record.Merge(other, MergeKeep); err != nil {
+		t.Fatal(err)
+	}
+	if record.ToString() != "<call:4>W1AW<band:3>20m<mode:2>CW<shoesize:1>9" {
+		t.Fatalf("Unexpected record %q", record.ToString())
+	}
+	if n, err := record.GetInt("shoesize"); err != nil || n != 9 {
+		t.Fatalf("Type not merged: %v %v", n, err)
+	}
+
+	record = newRecord()
+	record.Merge(other, MergeOverwrite)
+	if v, _ := record.GetValue("mode"); v != "SSB" {
+		t.Fatalf("Unexpected mode %q", v)
+	}
+
+	record = newRecord()
+	if err := record.Merge(other, MergeStrict); !errors.Is(err, MergeConflict) {
+		t.Fatalf("Expected %v, got %v", MergeConflict, err)
+	}
+	if record.Has("shoesize") {
+		t.Fatal("Record changed by a failed merge")
+	}
+	if err := record.Merge(other, MergePolicy(-1)); err != InvalidMergePolicy {
+		t.Fatalf("Expected %v, got %v", InvalidMergePolicy, err)
+	}
+	if record.Has("shoesize") {
+		t.Fatal("Record changed by a merge with an unknown policy")
+	}
+}
@@ -0,0 +1,283 @@
+package adifparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors
+var TypeMismatch = errors.New("Field type does not match.")
+var InvalidValue = errors.New("Invalid value for data type.")
+
+// Error converting a field value to a typed value
+type ConversionError struct {
+	// Field name
+	Field string
+	// Raw field value
+	Value string
+	// Name of the requested data type
+	Type string
+	// Underlying error (TypeMismatch, InvalidValue, ...)
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("Cannot convert field %s value %q to %s: %v",
+		e.Field, e.Value, e.Type, e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// Parse an ADIF Boolean ("Y" or "N", case-insensitive)
+func parseADIFBoolean(s string) (bool, error) {
+	switch s {
+	case "Y", "y":
+		return true, nil
+	case "N", "n":
+		return false, nil
+	}
+	return false, InvalidValue
+}
+
+// Parse an ADIF Number: optional minus sign, digits and an optional
+// decimal point
+func parseADIFNumber(s string) (float64, error) {
+	digits := 0
+	seenPoint := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !seenPoint:
+			seenPoint = true
+		case c == '-' && i == 0:
+		default:
+			return 0, InvalidValue
+		}
+	}
+	if digits == 0 {
+		return 0, InvalidValue
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Parse an ADIF Date (YYYYMMDD) as midnight UTC
+func parseADIFDate(s string) (time.Time, error) {
+	if len(s) != 8 || !isDigits(s) {
+		return time.Time{}, InvalidValue
+	}
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return time.Time{}, InvalidValue
+	}
+	return t, nil
+}
+
+// Parse an ADIF Time (HHMM or HHMMSS), returned on the zero date in UTC
+func parseADIFTime(s string) (time.Time, error) {
+	var layout string
+	switch len(s) {
+	case 4:
+		layout = "1504"
+	case 6:
+		layout = "150405"
+	default:
+		return time.Time{}, InvalidValue
+	}
+	if !isDigits(s) {
+		return time.Time{}, InvalidValue
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, InvalidValue
+	}
+	return t, nil
+}
+
+// Parse an ADIF Location (XDDD MM.MMM) into signed decimal degrees
+func parseADIFLocation(s string) (float64, error) {
+	if len(s) != 11 || s[4] != ' ' || s[7] != '.' {
+		return 0, InvalidValue
+	}
+	if !isDigits(s[1:4]) || !isDigits(s[5:7]) || !isDigits(s[8:]) {
+		return 0, InvalidValue
+	}
+	deg, _ := strconv.Atoi(s[1:4])
+	min, _ := strconv.ParseFloat(s[5:], 64)
+	if min >= 60 {
+		return 0, InvalidValue
+	}
+	var sign float64
+	var limit int
+	switch charToUpper(s[0]) {
+	case 'N':
+		sign, limit = 1, 90
+	case 'S':
+		sign, limit = -1, 90
+	case 'E':
+		sign, limit = 1, 180
+	case 'W':
+		sign, limit = -1, 180
+	default:
+		return 0, InvalidValue
+	}
+	value := float64(deg) + min/60
+	if value > float64(limit) {
+		return 0, InvalidValue
+	}
+	return sign * value, nil
+}
+
+// Whether a string is non-empty and consists only of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether a data type can be read as a Number
+func isNumericType(datatype int) bool {
+	switch datatype {
+	case ADIFNumber, ADIFInteger, ADIFPositiveInteger:
+		return true
+	}
+	return false
+}
+
+// Whether a data type has a strict format, as opposed to free text
+func isStrictType(datatype int) bool {
+	switch datatype {
+	case ADIFBoolean, ADIFDate, ADIFTime, ADIFLocation:
+		return true
+	}
+	return isNumericType(datatype)
+}
+
+// Whether a field of type actual can be read as type wanted.  Free text
+// fields can be read as any type.
+func typeCompatible(wanted, actual int) bool {
+	if wanted == actual || !isStrictType(actual) {
+		return true
+	}
+	return isNumericType(wanted) && isNumericType(actual)
+}
+
+// Get the data type of a field: the explicit type indicator if present,
+// otherwise the type from the field definitions or USERDEF declarations
+func (r *baseADIFRecord) fieldType(name string) int {
+	if code := declaredTypeCode(r, name); code != 0 {
+		if datatype, ok := typeCodeMap[code]; ok {
+			return datatype
+		}
+	}
+	if info, ok := ADIFfieldInfo[name]; ok {
+		return info.DataType
+	}
+	return ADIFString
+}
+
+// Get a value for typed conversion, checking the field's data type
+func (r *baseADIFRecord) typedValue(name string, wanted int) (string, string, error) {
+	name = strings.ToLower(name)
+	v, ok := r.values[name]
+	if !ok {
+		return name, "", NoSuchField
+	}
+	if !typeCompatible(wanted, r.fieldType(name)) {
+		return name, v, &ConversionError{name, v, adifTypeNames[wanted], TypeMismatch}
+	}
+	return name, strings.TrimSpace(v), nil
+}
+
+// Get a Number field as a float64
+func (r *baseADIFRecord) GetFloat(name string) (float64, error) {
+	name, v, err := r.typedValue(name, ADIFNumber)
+	if err != nil {
+		return 0, err
+	}
+	f, err := parseADIFNumber(v)
+	if err != nil {
+		return 0, &ConversionError{name, v, adifTypeNames[ADIFNumber], err}
+	}
+	return f, nil
+}
+
+// Get a Number field as an integer
+func (r *baseADIFRecord) GetInt(name string) (int, error) {
+	name, v, err := r.typedValue(name, ADIFNumber)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := parseADIFNumber(v); err != nil {
+		return 0, &ConversionError{name, v, "Integer", err}
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, &ConversionError{name, v, "Integer", err}
+	}
+	return i, nil
+}
+
+// Get a Boolean field
+func (r *baseADIFRecord) GetBool(name string) (bool, error) {
+	name, v, err := r.typedValue(name, ADIFBoolean)
+	if err != nil {
+		return false, err
+	}
+	b, err := parseADIFBoolean(v)
+	if err != nil {
+		return false, &ConversionError{name, v, adifTypeNames[ADIFBoolean], err}
+	}
+	return b, nil
+}
+
+// Get a Date field as midnight UTC on that date
+func (r *baseADIFRecord) GetDate(name string) (time.Time, error) {
+	name, v, err := r.typedValue(name, ADIFDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseADIFDate(v)
+	if err != nil {
+		return time.Time{}, &ConversionError{name, v, adifTypeNames[ADIFDate], err}
+	}
+	return t, nil
+}
+
+// Get a Time field as a time of day on the zero date in UTC
+func (r *baseADIFRecord) GetTime(name string) (time.Time, error) {
+	name, v, err := r.typedValue(name, ADIFTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseADIFTime(v)
+	if err != nil {
+		return time.Time{}, &ConversionError{name, v, adifTypeNames[ADIFTime], err}
+	}
+	return t, nil
+}
+
+// Get a Location field as signed decimal degrees (north and east positive)
+func (r *baseADIFRecord) GetLocation(name string) (float64, error) {
+	name, v, err := r.typedValue(name, ADIFLocation)
+	if err != nil {
+		return 0, err
+	}
+	l, err := parseADIFLocation(v)
+	if err != nil {
+		return 0, &ConversionError{name, v, adifTypeNames[ADIFLocation], err}
+	}
+	return l, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Matir/adifparser"
+	"os"
+)
+
+// A problem found in an input file
+type problem struct {
+	File     string `json:"file"`
+	Record   int    `json:"record"`
+	Offset   int64  `json:"offset"`
+	Severity string `json:"severity"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p problem) String() string {
+	if p.Field == "" {
+		return fmt.Sprintf("%s: record %d (offset %d): %s: %s",
+			p.File, p.Record, p.Offset, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: record %d (offset %d): %s: %s: %s",
+		p.File, p.Record, p.Offset, p.Severity, p.Field, p.Message)
+}
+
+func main() {
+	var jsonOutput = flag.Bool("json", false, "Output problems as JSON.")
+	var warnings = flag.Bool("warnings", true, "Report warnings as well as errors.")
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprint(os.Stderr, "Need at least one input file.\n")
+		os.Exit(2)
+	}
+
+	problems := make([]problem, 0)
+	failed := false
+	for _, filename := range flag.Args() {
+		found, err := validateFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			failed = true
+			continue
+		}
+		for _, p := range found {
+			if p.Severity == adifparser.SeverityError.String() {
+				failed = true
+			} else if !*warnings {
+				continue
+			}
+			problems = append(problems, p)
+		}
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(problems)
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// Describe a syntax error, using the position from a ParseError if there
+// is one
+func parseProblem(filename string, reader adifparser.ADIFReader, err error) problem {
+	p := problem{
+		File:     filename,
+		Record:   reader.RecordCount() + 1,
+		Offset:   -1,
+		Severity: adifparser.SeverityError.String(),
+		Message:  err.Error(),
+	}
+	var perr *adifparser.ParseError
+	if errors.As(err, &perr) {
+		p.Record = perr.Record
+		p.Offset = perr.Offset
+		p.Message = fmt.Sprintf("line %d, column %d: %v", perr.Line, perr.Column, perr.Err)
+		if perr.Tag != "" {
+			p.Message += fmt.Sprintf(" in tag %q", perr.Tag)
+		}
+	}
+	return p
+}
+
+// Read every record of a file, collecting syntax and validation problems
+func validateFile(filename string) ([]problem, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var problems []problem
+	var reader adifparser.ADIFReader
+	// Report malformed records and carry on with the next one
+	skippedCount := 0
+	recovery := adifparser.WithRecovery(func(skipped adifparser.SkippedRecord) {
+		problems = append(problems, parseProblem(filename, reader, skipped.Err))
+		skippedCount++
+	})
+	reader = adifparser.NewAutoReader(fp, recovery)
+	for record, err := range reader.All() {
+		if err != nil {
+			// The reader cannot continue after this error
+			problems = append(problems, parseProblem(filename, reader, err))
+			break
+		}
+		for _, issue := range adifparser.Validate(record) {
+			problems = append(problems, problem{
+				File:     filename,
+				Record:   reader.RecordCount() + skippedCount,
+				Offset:   reader.RecordOffset(),
+				Severity: issue.Severity.String(),
+				Field:    issue.Field,
+				Message:  issue.Message,
+			})
+		}
+	}
+	return problems, nil
+}
@@ -5,33 +5,98 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 var OutputStarted = errors.New("Output already started.")
+var NonASCIIValue = errors.New("Non-ASCII characters in a non-Intl field.")
+var InvalidUTF8 = errors.New("Invalid UTF-8 in field value.")
+var InvalidPreamble = errors.New("Preamble contains a field or <eoh> tag.")
 
 // Basic writer type
 type ADIFWriter interface {
 	WriteRecord(ADIFRecord) error
 	Flush() error
+	// Finish the output and flush it
+	Close() error
 	SetComment(string) error
+	WriteHeader(ADIFHeader) error
 }
 
 type baseADIFWriter struct {
 	writer  *bufio.Writer
 	started bool
+	// Whether or not the header has been written
+	headerWritten bool
+	// Options
+	config writerConfig
+	// Output held back for USERDEF declarations
+	pending *userDefOutput
 }
 
+// Preamble used when a header is written without one
+const defaultPreamble = "Generated by " + defaultProgramID
+
 // Construct a new writer
-func NewADIFWriter(w io.Writer) *baseADIFWriter {
+func NewADIFWriter(w io.Writer, opts ...WriterOption) *baseADIFWriter {
 	writer := &baseADIFWriter{}
 	writer.writer = bufio.NewWriter(w)
 	writer.started = false
+	writer.config = newWriterConfig(opts)
+	if writer.config.userDefs {
+		writer.pending = newUserDefOutput(w, writer.config.userDefLimit)
+		writer.writer = bufio.NewWriter(&writer.pending.buf)
+	}
 	return writer
 }
 
+// Write a record.  Non-ASCII characters are only allowed in Intl fields.
 func (writer *baseADIFWriter) WriteRecord(r ADIFRecord) error {
+	err := writer.writeRecord(r)
+	if err == nil && writer.pending != nil {
+		writer.pending.add(r)
+		if writer.pending.full(writer.writer) {
+			err = writer.release()
+		}
+	}
+	return err
+}
+
+func (writer *baseADIFWriter) writeRecord(r ADIFRecord) error {
+	if record, ok := r.(*baseADIFRecord); ok && record.source != nil &&
+		writer.canReuse(record.source.raw, record.source.options) {
+		return writer.writeSource(record)
+	}
+	fields := writer.config.fieldOrder(r)
+	values := make([]string, len(fields))
+	for i, name := range fields {
+		value, err := writer.encodeValue(r, name)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	var comments []Comment
+	if writer.config.comments {
+		comments = r.Comments()
+		if err := checkComments(comments); err != nil {
+			return err
+		}
+	}
 	writer.started = true
-	_, err := fmt.Fprintf(writer.writer, "%s<eor>\n", r.ToString())
+	// Comments that can't follow a field go before the first one
+	if comments != nil {
+		writer.writeComments(otherComments(comments, fields))
+	}
+	for i, name := range fields {
+		writer.writer.WriteString(writer.serializeField(outputName(r, name), values[i], recordTypeCode(r, name)))
+		if comments != nil {
+			writer.writeComments(commentsAfter(comments, name))
+		}
+	}
+	_, err := writer.writer.WriteString("<eor>\n")
 	if err != nil {
 		// TODO: log
 		return err
@@ -39,14 +104,225 @@ func (writer *baseADIFWriter) WriteRecord(r ADIFRecord) error {
 	return nil
 }
 
+// Write comment or preamble text, in the output character set where
+// possible
+func (writer *baseADIFWriter) writeComments(text string) {
+	if encoded, err := writer.config.charset.encode(text); err == nil {
+		text = encoded
+	}
+	writer.writer.WriteString(text)
+}
+
+// Check and encode the value of a field for output
+func (writer *baseADIFWriter) encodeValue(r ADIFRecord, name string) (string, error) {
+	value, _ := r.GetValue(name)
+	if writer.config.charset == CharsetRaw && !isIntlField(r, name) && hasNonASCII(value) {
+		return "", fmt.Errorf("%s: %w", name, NonASCIIValue)
+	}
+	value, err := writer.config.charset.encode(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return value, nil
+}
+
+// Serialize a field with its length counted according to the length mode
+func (writer *baseADIFWriter) serializeField(name, value string, typecode byte) string {
+	length := len(value)
+	if writer.config.lengthMode == LengthRunes && writer.config.charset == CharsetRaw {
+		length = utf8.RuneCountInString(value)
+	}
+	if typecode == 0 {
+		return fmt.Sprintf("<%s:%d>%s", name, length, value)
+	}
+	return fmt.Sprintf("<%s:%d:%c>%s", name, length, typecode, value)
+}
+
+// Whether a field of a record holds international characters
+func isIntlField(r ADIFRecord, name string) bool {
+	if info, ok := ADIFfieldInfo[name]; ok {
+		return info.IsIntl()
+	}
+	switch declaredTypeCode(r, name) {
+	case 'I', 'G':
+		return true
+	}
+	return false
+}
+
+func hasNonASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// Write text after the records, such as the trailer of a file read in
+// lossless mode
+func (writer *baseADIFWriter) WriteTrailer(text string) error {
+	writer.started = true
+	_, err := writer.writer.WriteString(text)
+	return err
+}
+
+// Write the output.  With WithOutputUserDefs, a header still held back is
+// written first, declaring the custom fields of the records written so far.
 func (writer *baseADIFWriter) Flush() error {
+	if err := writer.release(); err != nil {
+		return err
+	}
 	return writer.writer.Flush()
 }
 
+// Write the header and the records held back for USERDEF declarations
+func (writer *baseADIFWriter) release() error {
+	pending := writer.pending
+	if pending == nil {
+		return nil
+	}
+	writer.pending = nil
+	writer.writer.Flush()
+	writer.writer = pending.dest
+	if pending.header != nil || len(pending.defs) > 0 {
+		header := ADIFHeader{}
+		if pending.header != nil {
+			header = *pending.header
+		}
+		if err := writer.writeHeader(pending.declare(header)); err != nil {
+			return err
+		}
+	}
+	_, err := writer.writer.Write(pending.buf.Bytes())
+	return err
+}
+
+// Flush the output; ADI output needs no closing text
+func (writer *baseADIFWriter) Close() error {
+	return writer.Flush()
+}
+
+// Write a header consisting of the comment and the default header fields
 func (writer *baseADIFWriter) SetComment(comment string) error {
-	if writer.started {
+	return writer.WriteHeader(ADIFHeader{Preamble: comment})
+}
+
+// Write a header.  adif_ver and programid are filled in if they are empty.
+// A header read in lossless mode is written as it was read if it has not
+// been changed.
+func (writer *baseADIFWriter) WriteHeader(header ADIFHeader) error {
+	if writer.started || writer.headerWritten {
 		return OutputStarted
 	}
-	fmt.Fprintf(writer.writer, "%s<eoh>\n", comment)
-	return nil
+	// A rejected header leaves the writer as it was
+	if err := checkPreamble(header.Preamble); err != nil {
+		return err
+	}
+	if writer.config.comments {
+		if err := checkComments(header.comments); err != nil {
+			return err
+		}
+	}
+	writer.headerWritten = true
+	if writer.pending != nil {
+		writer.pending.header = &header
+		return nil
+	}
+	return writer.writeHeader(header)
+}
+
+// Write a header that has been checked
+func (writer *baseADIFWriter) writeHeader(header ADIFHeader) error {
+	if writer.config.programID != "" {
+		header.ProgramID = writer.config.programID
+	}
+	w := writer.writer
+	if source := header.source; source != nil && source.unmodified(&header) &&
+		writer.canReuse(source.raw, source.options) {
+		_, err := w.WriteString(source.raw)
+		return err
+	}
+	var comments []Comment
+	if writer.config.comments {
+		comments = header.comments
+	}
+	writer.writeComments(headerPreamble(header.Preamble))
+	w.WriteString("\n")
+	var names []string
+	writeLine := func(name, value string, typecode byte) {
+		names = append(names, name)
+		w.WriteString(writer.serializeField(name, value, typecode))
+		if text := commentsAfter(comments, name); text != "" {
+			writer.writeComments(text)
+		} else {
+			w.WriteString("\n")
+		}
+	}
+	for _, field := range headerFields(header) {
+		writeLine(field[0], field[1], 0)
+	}
+	for _, def := range header.UserDefs {
+		writeLine(fmt.Sprintf("userdef%d", def.ID), def.value(), def.TypeCode)
+	}
+	writer.writeComments(otherComments(comments, names))
+	_, err := w.WriteString("<eoh>\n")
+	return err
+}
+
+// The preamble to write, or the default one if it is empty
+func headerPreamble(preamble string) string {
+	if preamble == "" {
+		return defaultPreamble
+	}
+	return preamble
+}
+
+// Check that a preamble is read back as text.  Tags without a colon, such
+// as e-mail addresses, are kept in the preamble, but a field, <eoh> or a
+// "<" with no ">" after it would be read as part of the header.  ADI has
+// no way to escape "<".
+func checkPreamble(preamble string) error {
+	rest := preamble
+	for {
+		start := strings.IndexByte(rest, '<')
+		if start == -1 {
+			return nil
+		}
+		rest = rest[start+1:]
+		end := strings.IndexByte(rest, '>')
+		if end == -1 || strings.IndexByte(rest[:end], ':') != -1 || strings.EqualFold(rest[:end], "eoh") {
+			return InvalidPreamble
+		}
+		rest = rest[end+1:]
+	}
+}
+
+// Name and value of the simple header fields to write, in order
+func headerFields(header ADIFHeader) [][2]string {
+	version := header.Version
+	if version == "" {
+		version = ADIFVersion
+	}
+	programid := header.ProgramID
+	if programid == "" {
+		programid = defaultProgramID
+	}
+	fields := [][2]string{{"adif_ver", version}}
+	if header.CreatedTimestamp != "" {
+		fields = append(fields, [2]string{"created_timestamp", header.CreatedTimestamp})
+	}
+	fields = append(fields, [2]string{"programid", programid})
+	if header.ProgramVersion != "" {
+		fields = append(fields, [2]string{"programversion", header.ProgramVersion})
+	}
+	appnames := make([]string, 0, len(header.AppFields))
+	for name := range header.AppFields {
+		appnames = append(appnames, name)
+	}
+	sort.Strings(appnames)
+	for _, name := range appnames {
+		fields = append(fields, [2]string{header.appName(name), header.AppFields[name]})
+	}
+	return fields
 }
@@ -0,0 +1,377 @@
+package adifparser
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteHeader(t *testing.T) {
+	header := ADIFHeader{
+		Preamble:         "Test export",
+		ProgramVersion:   "2.1",
+		CreatedTimestamp: "20240101 120000",
+		UserDefs: []UserDef{
+			{ID: 1, Name: "EPC", TypeCode: 'N'},
+			{ID: 2, Name: "SweaterSize", TypeCode: 'E', Spec: "{S,M,L}"},
+		},
+		AppFields: map[string]string{"app_lotw_numrec": "1"},
+	}
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	if err := writer.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.SetComment("Again"); err != OutputStarted {
+		t.Fatalf("Expected %v, got %v", OutputStarted, err)
+	}
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+
+	reader := NewADIFReader(&buf)
+	got := reader.Header()
+	if got.Version != ADIFVersion || got.ProgramID != defaultProgramID ||
+		got.ProgramVersion != "2.1" || got.CreatedTimestamp != "20240101 120000" {
+		t.Fatalf("Unexpected header %+v", got)
+	}
+	if len(got.UserDefs) != 2 || got.UserDefs[1] != header.UserDefs[1] {
+		t.Fatalf("Unexpected userdefs %+v", got.UserDefs)
+	}
+	if got.AppFields["app_lotw_numrec"] != "1" {
+		t.Fatalf("Unexpected app fields %+v", got.AppFields)
+	}
+	if r, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	} else if v, _ := r.GetValue("call"); v != "W1AW" {
+		t.Fatalf("Unexpected call %q", v)
+	}
+}
+
+func TestWritePreambleWithTag(t *testing.T) {
+	for _, preamble := range []string{"<call:4>W1AW export", "Export <EOH> here", "a < b"} {
+		var buf bytes.Buffer
+		writer := NewADIFWriter(&buf)
+		if err := writer.SetComment(preamble); err != InvalidPreamble {
+			t.Fatalf("Expected %v, got %v", InvalidPreamble, err)
+		}
+		writer.Flush()
+		if buf.Len() != 0 {
+			t.Fatalf("Unexpected output %q", buf.String())
+		}
+		// The writer still takes a header
+		if err := writer.SetComment("Export"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWritePreambleWithAddress(t *testing.T) {
+	input := "Exported by Joe <joe@example.com>\n<adif_ver:5>3.1.4\n<eoh>\n"
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	if err := writer.WriteHeader(*NewADIFReader(strings.NewReader(input)).Header()); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if got := NewADIFReader(&buf).Header().Preamble; got != "Exported by Joe <joe@example.com>" {
+		t.Fatalf("Unexpected preamble %q", got)
+	}
+}
+
+func TestWriteHeaderAppFieldCase(t *testing.T) {
+	input := "LoTW\n<PROGRAMID:4>LoTW\n<APP_LoTW_LASTQSL:19>2015-06-02 21:02:09\n<eoh>\n"
+	header := *NewADIFReader(strings.NewReader(input)).Header()
+	if v, err := header.GetAppField("app_lotw_lastqsl"); err != nil || v != "2015-06-02 21:02:09" {
+		t.Fatalf("Unexpected value %q (%v)", v, err)
+	}
+
+	var adx bytes.Buffer
+	writer := NewADXWriter(&adx)
+	writer.WriteHeader(header)
+	writer.Close()
+	if !strings.Contains(adx.String(), `<APP PROGRAMID="LoTW" FIELDNAME="LASTQSL">`) {
+		t.Fatalf("Case not kept:\n%s", adx.String())
+	}
+
+	// The case read from ADX is kept in ADI output
+	var buf bytes.Buffer
+	adi := NewADIFWriter(&buf)
+	adi.WriteHeader(*NewADXReader(&adx).Header())
+	adi.Flush()
+	if !strings.Contains(buf.String(), "<APP_LoTW_LASTQSL:19>") {
+		t.Fatalf("Case not kept: %q", buf.String())
+	}
+}
+
+func TestWriteHeaderAfterRecord(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	writer.WriteRecord(NewADIFRecord())
+	if err := writer.WriteHeader(ADIFHeader{}); err != OutputStarted {
+		t.Fatalf("Expected %v, got %v", OutputStarted, err)
+	}
+}
+
+func TestSetCommentWritesVersion(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	writer.SetComment("")
+	writer.Flush()
+	out := buf.String()
+	if !strings.HasPrefix(out, defaultPreamble) {
+		t.Fatalf("Expected default preamble, got:\n%s", out)
+	}
+	if !strings.Contains(out, "<adif_ver:5>"+ADIFVersion) {
+		t.Fatalf("Expected adif_ver, got:\n%s", out)
+	}
+}
+
+func TestWriteNonASCII(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	record := NewADIFRecord()
+	record.SetValue("name", "Jürgen")
+	if err := writer.WriteRecord(record); !errors.Is(err, NonASCIIValue) {
+		t.Fatalf("Expected %v, got %v", NonASCIIValue, err)
+	}
+
+	record = NewADIFRecord()
+	record.SetValue("name_intl", "Jürgen")
+	record.SetTypedValue("my_note", "Grüße", 'I')
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if !strings.Contains(buf.String(), "<name_intl:7>Jürgen") {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	writer = NewADIFWriter(&buf, WithOutputLengthMode(LengthRunes))
+	writer.WriteRecord(record)
+	writer.Flush()
+	if !strings.Contains(buf.String(), "<name_intl:6>Jürgen") {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+	reader := NewADIFReader(&buf, WithLengthMode(LengthRunes))
+	if got, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	} else if v, _ := got.GetValue("my_note"); v != "Grüße" {
+		t.Fatalf("Unexpected my_note %q", v)
+	}
+
+	// Explicit UTF-8 output allows non-ASCII in any field
+	buf.Reset()
+	writer = NewADIFWriter(&buf, WithOutputCharset(CharsetUTF8))
+	record = NewADIFRecord()
+	record.SetValue("name", "Jürgen")
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if buf.String() != "<name:7>Jürgen<eor>\n" {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+}
+
+func TestWriteCharset(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf, WithOutputCharset(CharsetWindows1252))
+	record := NewADIFRecord()
+	record.SetValue("name", "Jürgen €")
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if !strings.Contains(buf.String(), "<name:8>J\xfcrgen \x80") {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+	reader := NewADIFReader(&buf, WithCharset(CharsetWindows1252))
+	if got, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	} else if v, _ := got.GetValue("name"); v != "Jürgen €" {
+		t.Fatalf("Unexpected name %q", v)
+	}
+
+	writer = NewADIFWriter(&buf, WithOutputCharset(CharsetISO88591))
+	if err := writer.WriteRecord(record); !errors.Is(err, UnrepresentableCharacter) {
+		t.Fatalf("Expected %v, got %v", UnrepresentableCharacter, err)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	input := "<zz_b:1>b<call:4>W1AW<zz_a:1>a<band:3>20m<app_x_c:1>c<eor>"
+	cases := []struct {
+		order    FieldOrder
+		expected string
+	}{
+		{nil, "<call:4>W1AW<band:3>20m<app_x_c:1>c<zz_a:1>a<zz_b:1>b<eor>"},
+		{SpecOrder, "<call:4>W1AW<band:3>20m<app_x_c:1>c<zz_a:1>a<zz_b:1>b<eor>"},
+		{InputOrder, "<zz_b:1>b<call:4>W1AW<zz_a:1>a<band:3>20m<app_x_c:1>c<eor>"},
+		{ColumnOrder("BAND", "zz_b", "qso_date"), "<band:3>20m<zz_b:1>b<call:4>W1AW<app_x_c:1>c<zz_a:1>a<eor>"},
+	}
+	for _, c := range cases {
+		record, err := NewADIFReader(strings.NewReader(input)).ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var opts []WriterOption
+		if c.order != nil {
+			opts = append(opts, WithFieldOrder(c.order))
+		}
+		// The output must not depend on map iteration order
+		for i := 0; i < 10; i++ {
+			var buf bytes.Buffer
+			writer := NewADIFWriter(&buf, opts...)
+			writer.WriteRecord(record)
+			writer.Flush()
+			if got := strings.TrimSpace(buf.String()); got != c.expected {
+				t.Fatalf("Expected %q, got %q", c.expected, got)
+			}
+		}
+	}
+
+	// Input order follows changes to the record
+	record := NewADIFRecord()
+	record.SetValue("zz_b", "b")
+	record.SetValue("call", "W1AW")
+	record.SetValue("zz_a", "a")
+	record.DeleteField("call")
+	record.SetValue("call", "KF4MD")
+	if got := strings.Join(InputOrder(record), ","); got != "zz_b,zz_a,call" {
+		t.Fatalf("Unexpected order %q", got)
+	}
+	if got := record.ToString(); got != "<call:5>KF4MD<zz_a:1>a<zz_b:1>b" {
+		t.Fatalf("Unexpected ToString %q", got)
+	}
+}
+
+func TestWriteComments(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+	record.SetValue("band", "20m")
+	record.SetComments([]Comment{{"call", "// first"}, {"", "# note"}, {"band", "// second"}})
+
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	writer.WriteRecord(record)
+	writer.Flush()
+	if got := buf.String(); got != "<call:4>W1AW<band:3>20m<eor>\n" {
+		t.Fatalf("Unexpected output %q", got)
+	}
+
+	buf.Reset()
+	writer = NewADIFWriter(&buf, WithOutputComments())
+	header := ADIFHeader{Preamble: "Test"}
+	header.SetComments([]Comment{{"adif_ver", "// version"}})
+	if err := writer.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	expected := "Test\n<adif_ver:5>3.1.4 // version\n<programid:10>adifparser\n<eoh>\n" +
+		"# note\n<call:4>W1AW // first\n<band:3>20m // second\n<eor>\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+
+	// The comments are read back
+	reader := NewADIFReader(&buf, WithComments())
+	got, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Comments(), []Comment{{"", "# note"}, {"call", "// first"}, {"band", "// second"}}) {
+		t.Fatalf("Unexpected comments %v", got.Comments())
+	}
+
+	record.SetComments([]Comment{{"call", "<bad>"}})
+	if err := writer.WriteRecord(record); !errors.Is(err, InvalidComment) {
+		t.Fatalf("Expected %v, got %v", InvalidComment, err)
+	}
+}
+
+func TestWriteUserDefs(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+	record.SetValue("epc", "12")
+	record.SetTypedValue("shoesize", "9", 'N')
+	record.SetValue("app_test_note", "x")
+
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf, WithOutputUserDefs(0))
+	header := ADIFHeader{Preamble: "Test", UserDefs: []UserDef{{ID: 1, Name: "EPC", TypeCode: 'N'}}}
+	if err := writer.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Output written before Flush: %q", buf.String())
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Test\n<adif_ver:5>3.1.4\n<programid:10>adifparser\n" +
+		"<userdef1:3:N>EPC\n<userdef2:8:N>shoesize\n<eoh>\n" +
+		"<call:4>W1AW<app_test_note:1>x<epc:2>12<shoesize:1:N>9<eor>\n"
+	if got := buf.String(); got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+	if len(header.UserDefs) != 1 {
+		t.Fatal("Header changed")
+	}
+
+	// Without a header, one is written for the declarations
+	buf.Reset()
+	writer = NewADIFWriter(&buf, WithOutputUserDefs(0))
+	writer.WriteRecord(record)
+	writer.Flush()
+	reader := NewADIFReader(&buf)
+	defs := reader.Header().UserDefs
+	if len(defs) != 2 || defs[0].Name != "epc" || defs[0].TypeCode != 'S' || defs[1].TypeCode != 'N' {
+		t.Fatalf("Unexpected declarations %+v", defs)
+	}
+
+	// Past the limit, the header is written with the fields seen so far
+	buf.Reset()
+	writer = NewADIFWriter(&buf, WithOutputUserDefs(10))
+	writer.WriteRecord(record)
+	later := NewADIFRecord()
+	later.SetValue("sweatersize", "M")
+	writer.WriteRecord(later)
+	writer.Flush()
+	reader = NewADIFReader(&buf)
+	if defs := reader.Header().UserDefs; len(defs) != 2 || defs[1].Name != "shoesize" {
+		t.Fatalf("Unexpected declarations %+v", defs)
+	}
+}
+
+func TestWriteProgramID(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf, WithOutputProgramID("MyLog"))
+	if err := writer.WriteHeader(ADIFHeader{Preamble: "Test", ProgramID: "LoTW"}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+	if got := NewADIFReader(&buf).Header().ProgramID; got != "MyLog" {
+		t.Fatalf("Unexpected program id %q", got)
+	}
+
+	buf.Reset()
+	adx := NewADXWriter(&buf, WithOutputProgramID("MyLog"))
+	adx.Close()
+	if !strings.Contains(buf.String(), "<PROGRAMID>MyLog</PROGRAMID>") {
+		t.Fatalf("Unexpected output %q", buf.String())
+	}
+}
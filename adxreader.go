@@ -0,0 +1,354 @@
+package adifparser
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// Reader for ADX (ADIF XML) files
+type adxReader struct {
+	// Underlying XML decoder
+	dec *xml.Decoder
+	// Cancellable source
+	ctxrdr *contextReader
+	// Parsed header
+	header *ADIFHeader
+	// USERDEF declarations of the header, by lowercase name
+	userDefs map[string]UserDef
+	// Whether or not the header has been read
+	headerRead bool
+	// Record count
+	records int
+	// Offset and length of the most recent record
+	recordOffset int64
+	recordLength int64
+	// Options
+	config readerConfig
+	// Count of records skipped in recovery mode
+	skipped int
+	// Input read by the decoder, kept in recovery mode
+	capture *adxCapture
+	// Whether the input is transcoded, so offsets don't match the input
+	transcoded bool
+	// Length of a byte order mark discarded before the decoder's input,
+	// added to the offsets returned
+	base int64
+}
+
+// Reader keeping the bytes the XML decoder reads.  It is an io.ByteReader,
+// so the decoder doesn't read ahead of its offset.
+type adxCapture struct {
+	r *bufio.Reader
+	// Offset of the first byte kept
+	start int64
+	buf   []byte
+}
+
+func (c *adxCapture) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.buf = append(c.buf, b)
+	}
+	return b, err
+}
+
+func (c *adxCapture) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.buf = append(c.buf, p[:n]...)
+	return n, err
+}
+
+// Stop keeping the bytes before offset
+func (c *adxCapture) discard(offset int64) {
+	n := int(offset - c.start)
+	c.buf = c.buf[:copy(c.buf, c.buf[n:])]
+	c.start = offset
+}
+
+// Copy of the bytes from offset start to end
+func (c *adxCapture) bytes(start, end int64) []byte {
+	return append([]byte(nil), c.buf[start-c.start:end-c.start]...)
+}
+
+// Character data and attributes of an ADX element
+type adxElement struct {
+	Value     string `xml:",chardata"`
+	FieldID   string `xml:"FIELDID,attr"`
+	FieldName string `xml:"FIELDNAME,attr"`
+	ProgramID string `xml:"PROGRAMID,attr"`
+	Type      string `xml:"TYPE,attr"`
+	Enum      string `xml:"ENUM,attr"`
+	Range     string `xml:"RANGE,attr"`
+}
+
+func NewADXReader(r io.Reader, opts ...ReaderOption) *adxReader {
+	reader := &adxReader{}
+	reader.config = newReaderConfig(opts)
+	reader.ctxrdr = newContextReader(r)
+	if reader.config.recover {
+		reader.capture = &adxCapture{r: bufio.NewReader(reader.ctxrdr)}
+		reader.dec = xml.NewDecoder(reader.capture)
+	} else {
+		reader.dec = xml.NewDecoder(reader.ctxrdr)
+	}
+	reader.dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		r, err := xmlCharsetReader(label, input)
+		reader.transcoded = r != input
+		return r, err
+	}
+	reader.header = newADIFHeader()
+	return reader
+}
+
+func (ardr *adxReader) ReadRecord() (ADIFRecord, error) {
+	return ardr.ReadRecordContext(context.Background())
+}
+
+func (ardr *adxReader) All() iter.Seq2[ADIFRecord, error] {
+	return readerSeq(ardr)
+}
+
+func (ardr *adxReader) Records(ctx context.Context) <-chan RecordResult {
+	return readerChan(ctx, ardr)
+}
+
+// Errors from the XML decoder are permanent, so the reader can't be used
+// again after a cancelled read.
+func (ardr *adxReader) ReadRecordContext(ctx context.Context) (ADIFRecord, error) {
+	ardr.ctxrdr.ctx = ctx
+	defer func() { ardr.ctxrdr.ctx = context.Background() }()
+	record, err := ardr.readNextRecord()
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return record, err
+}
+
+func (ardr *adxReader) readNextRecord() (ADIFRecord, error) {
+	for {
+		// Tokens are contiguous, so this is where the next one starts
+		offset := ardr.dec.InputOffset()
+		if ardr.capture != nil {
+			ardr.capture.discard(offset)
+		}
+		tok, err := ardr.dec.Token()
+		if err != nil {
+			if err != io.EOF {
+				adiflog.Printf("adx: %v", err)
+				err = ardr.parseError(err)
+			}
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(start.Name.Local) {
+		case "HEADER":
+			if err := ardr.readHeader(); err != nil {
+				return nil, ardr.parseError(err)
+			}
+		case "RECORDS":
+			ardr.headerRead = true
+		case "RECORD":
+			ardr.headerRead = true
+			ardr.recordOffset = offset
+			record, err := ardr.readRecord()
+			if err == InvalidField && ardr.config.recover {
+				if err := ardr.skipRecord(err); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, ardr.parseError(err)
+			}
+			ardr.records++
+			ardr.recordLength = ardr.dec.InputOffset() - offset
+			return record, nil
+		}
+	}
+}
+
+func (ardr *adxReader) RecordCount() int {
+	return ardr.records
+}
+
+func (ardr *adxReader) RecordOffset() int64 {
+	return ardr.base + ardr.recordOffset
+}
+
+func (ardr *adxReader) RecordLength() int64 {
+	return ardr.recordLength
+}
+
+// Get the file header, reading it if necessary
+func (ardr *adxReader) Header() *ADIFHeader {
+	for !ardr.headerRead {
+		tok, err := ardr.dec.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(start.Name.Local) {
+		case "HEADER":
+			ardr.readHeader()
+		case "RECORDS":
+			ardr.headerRead = true
+		}
+	}
+	return ardr.header
+}
+
+// Read the contents of a HEADER element
+func (ardr *adxReader) readHeader() error {
+	ardr.headerRead = true
+	var preamble []string
+	for {
+		tok, err := ardr.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.Comment:
+			preamble = append(preamble, strings.TrimSpace(string(t)))
+		case xml.EndElement:
+			ardr.header.Preamble = strings.Join(preamble, "\n")
+			ardr.userDefs = userDefMap(ardr.header.UserDefs)
+			return nil
+		case xml.StartElement:
+			elem := &adxElement{}
+			if err := ardr.dec.DecodeElement(elem, &t); err != nil {
+				return err
+			}
+			ardr.setHeaderElement(strings.ToLower(t.Name.Local), elem)
+		}
+	}
+}
+
+func (ardr *adxReader) setHeaderElement(name string, elem *adxElement) {
+	switch name {
+	case "userdef":
+		def := UserDef{}
+		def.ID, _ = strconv.Atoi(elem.FieldID)
+		def.Name = strings.TrimSpace(elem.Value)
+		def.TypeCode = adxTypeCode(elem.Type)
+		def.Spec = elem.Enum
+		if def.Spec == "" {
+			def.Spec = elem.Range
+		}
+		ardr.header.UserDefs = append(ardr.header.UserDefs, def)
+	case "app":
+		cased := adxAppFieldName(elem)
+		if cased != "" {
+			cased = "APP_" + elem.ProgramID + "_" + elem.FieldName
+		}
+		ardr.header.setAppField(cased, elem.Value)
+	default:
+		ardr.header.setElement(&elementData{
+			name:     name,
+			value:    elem.Value,
+			hasValue: true,
+		})
+	}
+}
+
+// Read the contents of a RECORD element
+func (ardr *adxReader) readRecord() (*baseADIFRecord, error) {
+	record := NewADIFRecord()
+	record.userDefs = ardr.userDefs
+	for {
+		tok, err := ardr.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return record, nil
+		case xml.StartElement:
+			elem := &adxElement{}
+			if err := ardr.dec.DecodeElement(elem, &t); err != nil {
+				return nil, err
+			}
+			name := strings.ToLower(t.Name.Local)
+			cased := ""
+			switch name {
+			case "app":
+				name = adxAppFieldName(elem)
+				cased = "APP_" + elem.ProgramID + "_" + elem.FieldName
+			case "userdef":
+				name = strings.ToLower(elem.FieldName)
+			}
+			if name == "" {
+				return nil, InvalidField
+			}
+			record.set(name, elem.Value)
+			if code := adxTypeCode(elem.Type); code != 0 {
+				record.setType(name, code)
+			}
+			if cased != "" {
+				record.setAppName(name, cased)
+			}
+		}
+	}
+}
+
+// Skip the rest of a record with invalid contents in recovery mode
+func (ardr *adxReader) skipRecord(cause error) error {
+	perr := ardr.parseError(cause).(*ParseError)
+	if err := ardr.dec.Skip(); err != nil {
+		return ardr.parseError(err)
+	}
+	ardr.skipped++
+	if ardr.config.onSkip != nil {
+		var raw []byte
+		if !ardr.transcoded {
+			raw = ardr.capture.bytes(ardr.recordOffset, ardr.dec.InputOffset())
+		}
+		ardr.config.onSkip(SkippedRecord{Raw: raw, Err: perr})
+	}
+	return nil
+}
+
+// Wrap an error with the current position in the input
+func (ardr *adxReader) parseError(err error) error {
+	line, column := ardr.dec.InputPos()
+	record := 0
+	if ardr.headerRead {
+		record = ardr.records + ardr.skipped + 1
+	}
+	return &ParseError{
+		Offset: ardr.base + ardr.dec.InputOffset(),
+		Line:   line,
+		Column: column,
+		Record: record,
+		Err:    err,
+	}
+}
+
+// Field name for an APP element: app_<programid>_<fieldname>
+func adxAppFieldName(elem *adxElement) string {
+	if elem.ProgramID == "" || elem.FieldName == "" {
+		return ""
+	}
+	return strings.ToLower("app_" + elem.ProgramID + "_" + elem.FieldName)
+}
+
+// Data type indicator from a TYPE attribute
+func adxTypeCode(t string) byte {
+	if len(t) != 1 {
+		return 0
+	}
+	return charToUpper(t[0])
+}
@@ -0,0 +1,145 @@
+package adifparser
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestADXReader(t *testing.T) {
+	f, err := os.Open("testdata/sample.adx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var reader ADIFReader = NewADXReader(f)
+	header := reader.Header()
+	if header.Version != "3.1.4" || header.ProgramID != "monolog" ||
+		header.ProgramVersion != "1.2" || header.CreatedTimestamp != "20240101 120000" {
+		t.Fatalf("Unexpected header %+v", header)
+	}
+	if header.Preamble != "Exported by a test logger" {
+		t.Fatalf("Unexpected preamble %q", header.Preamble)
+	}
+	if len(header.UserDefs) != 3 {
+		t.Fatalf("Expected 3 userdefs, got %d", len(header.UserDefs))
+	}
+	if def := header.UserDefs[1]; def.ID != 2 || def.Name != "SWEATERSIZE" ||
+		def.TypeCode != 'E' || def.Spec != "{S,M,L}" {
+		t.Fatalf("Unexpected userdef %+v", def)
+	}
+	if v, _ := header.GetAppField("app_lotw_numrec"); v != "2" {
+		t.Fatalf("Unexpected app_lotw_numrec %q", v)
+	}
+
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"qso_date":                "19900620",
+		"time_on":                 "1523",
+		"call":                    "VK9NS",
+		"freq":                    "14.080",
+		"name_intl":               "Jürgen",
+		"sweatersize":             "M",
+		"shoesize":                "11",
+		"app_monolog_compression": "off",
+	}
+	for field, value := range expected {
+		if v, err := record.GetValue(field); err != nil {
+			t.Fatalf("%s: %v", field, err)
+		} else if v != value {
+			t.Fatalf("Expected %s to be %q, got %q", field, value, v)
+		}
+	}
+	if f, err := record.GetFloat("freq"); err != nil || f != 14.08 {
+		t.Fatalf("Unexpected freq %v (%v)", f, err)
+	}
+
+	record, err = reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("comment"); v != "Tom & Jerry <test>" {
+		t.Fatalf("Unexpected comment %q", v)
+	}
+
+	if _, err := reader.ReadRecord(); err != io.EOF {
+		t.Fatalf("Expected %v, got %v", io.EOF, err)
+	}
+	if reader.RecordCount() != 2 {
+		t.Fatalf("Expected 2 records, got %d", reader.RecordCount())
+	}
+}
+
+func TestADXReaderNoHeader(t *testing.T) {
+	buf := strings.NewReader("<ADX><RECORDS><RECORD><CALL>W1AW</CALL></RECORD></RECORDS></ADX>")
+	reader := NewADXReader(buf)
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("call"); v != "W1AW" {
+		t.Fatalf("Unexpected call %q", v)
+	}
+	if reader.Header().Version != "" {
+		t.Fatalf("Expected empty header, got %+v", reader.Header())
+	}
+}
+
+func TestADXRecordOffset(t *testing.T) {
+	input := "<ADX><RECORDS>\n  <RECORD><CALL>W1AW</CALL></RECORD>\n" +
+		"  <RECORD><CALL>K1AB</CALL></RECORD></RECORDS></ADX>"
+	reader := NewADXReader(strings.NewReader(input))
+	for i := 0; i < 2; i++ {
+		if _, err := reader.ReadRecord(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(input[reader.RecordOffset():], "<RECORD>") {
+			t.Fatalf("Offset %d does not point at a record", reader.RecordOffset())
+		}
+	}
+}
+
+func TestADXParseError(t *testing.T) {
+	input := "<ADX><RECORDS>\n<RECORD><CALL>W1AW</CALL></RECORD>\n<RECORD><CALL>W1AW</RECORD>"
+	reader := NewADXReader(strings.NewReader(input))
+	if _, err := reader.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := reader.ReadRecord()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected ParseError, got %v", err)
+	}
+	if perr.Line != 3 || perr.Record != 2 {
+		t.Fatalf("Unexpected error %+v", perr)
+	}
+}
+
+func TestADXRecovery(t *testing.T) {
+	input := "<ADX><RECORDS><RECORD><CALL>K1AB</CALL><APP FIELDNAME=\"X\">1</APP>" +
+		"<BAND>20M</BAND></RECORD><RECORD><CALL>W1AW</CALL></RECORD></RECORDS></ADX>"
+	var skipped []SkippedRecord
+	reader := NewADXReader(strings.NewReader(input), WithRecovery(func(s SkippedRecord) {
+		skipped = append(skipped, s)
+	}))
+	record, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := record.GetValue("call"); v != "W1AW" {
+		t.Fatalf("Unexpected call %q", v)
+	}
+	if len(skipped) != 1 || skipped[0].Err.Record != 1 || skipped[0].Err.Err != InvalidField {
+		t.Fatalf("Unexpected skipped records %+v", skipped)
+	}
+	expected := "<RECORD><CALL>K1AB</CALL><APP FIELDNAME=\"X\">1</APP><BAND>20M</BAND></RECORD>"
+	if string(skipped[0].Raw) != expected {
+		t.Fatalf("Expected raw %q, got %q", expected, skipped[0].Raw)
+	}
+}
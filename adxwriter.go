@@ -0,0 +1,251 @@
+package adifparser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var OutputFinished = errors.New("Output already finished.")
+
+// Writer for ADX (ADIF XML) files
+type adxWriter struct {
+	writer *bufio.Writer
+	// Whether or not records have been written
+	started bool
+	// Whether or not the header has been written
+	headerWritten bool
+	// Whether or not the document has been closed
+	finished bool
+	// Options
+	config writerConfig
+	// Output held back for USERDEF declarations
+	pending *userDefOutput
+	// Lowercase names of the fields declared in the header
+	declared map[string]bool
+}
+
+// Construct a new ADX writer
+func NewADXWriter(w io.Writer, opts ...WriterOption) *adxWriter {
+	writer := &adxWriter{}
+	writer.writer = bufio.NewWriter(w)
+	writer.config = newWriterConfig(opts)
+	if writer.config.userDefs {
+		writer.pending = newUserDefOutput(w, writer.config.userDefLimit)
+		writer.writer = bufio.NewWriter(&writer.pending.buf)
+	}
+	return writer
+}
+
+// Write a record.  Values must be valid UTF-8.
+func (writer *adxWriter) WriteRecord(r ADIFRecord) error {
+	if writer.finished {
+		return OutputFinished
+	}
+	fields := writer.config.fieldOrder(r)
+	for _, name := range fields {
+		if value, _ := r.GetValue(name); !utf8.ValidString(value) {
+			return fmt.Errorf("%s: %w", name, InvalidUTF8)
+		}
+	}
+	if !writer.headerWritten {
+		writer.writeHeader(ADIFHeader{})
+	}
+	writer.started = true
+	writer.writer.WriteString("    <RECORD>\n")
+	for _, name := range fields {
+		value, _ := r.GetValue(name)
+		writer.writeField(outputName(r, name), value, recordTypeCode(r, name))
+	}
+	_, err := writer.writer.WriteString("    </RECORD>\n")
+	if err == nil && writer.pending != nil {
+		writer.pending.add(r)
+		if writer.pending.full(writer.writer) {
+			writer.release()
+		}
+	}
+	return err
+}
+
+// Write the output so far.  With WithOutputUserDefs, a header still held
+// back is written first, declaring the custom fields of the records
+// written so far.
+func (writer *adxWriter) Flush() error {
+	writer.release()
+	return writer.writer.Flush()
+}
+
+// Write the header and the records held back for USERDEF declarations
+func (writer *adxWriter) release() {
+	pending := writer.pending
+	if pending == nil {
+		return
+	}
+	// The header goes before the records held back
+	writer.pending = nil
+	writer.writer.Flush()
+	writer.writer = pending.dest
+	header := ADIFHeader{}
+	if pending.header != nil {
+		header = *pending.header
+	}
+	writer.writeHeader(pending.declare(header))
+	writer.writer.Write(pending.buf.Bytes())
+}
+
+// Finish the ADX document and flush it; no records can be written
+// afterwards
+func (writer *adxWriter) Close() error {
+	if !writer.finished {
+		if !writer.headerWritten {
+			writer.writeHeader(ADIFHeader{})
+		}
+		writer.finished = true
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		writer.writer.WriteString("  </RECORDS>\n</ADX>\n")
+	}
+	return writer.writer.Flush()
+}
+
+// Write the header with the comment as an XML comment
+func (writer *adxWriter) SetComment(comment string) error {
+	return writer.WriteHeader(ADIFHeader{Preamble: comment})
+}
+
+// Write a header.  ADIF_VER and PROGRAMID are filled in if they are empty.
+func (writer *adxWriter) WriteHeader(header ADIFHeader) error {
+	if writer.started || writer.headerWritten {
+		return OutputStarted
+	}
+	writer.writeHeader(header)
+	return nil
+}
+
+func (writer *adxWriter) writeHeader(header ADIFHeader) {
+	writer.headerWritten = true
+	if writer.pending != nil {
+		writer.pending.header = &header
+		return
+	}
+	if writer.config.programID != "" {
+		header.ProgramID = writer.config.programID
+	}
+	writer.declared = make(map[string]bool, len(header.UserDefs))
+	for _, def := range header.UserDefs {
+		writer.declared[strings.ToLower(def.Name)] = true
+	}
+	w := writer.writer
+	w.WriteString(xml.Header)
+	w.WriteString("<ADX>\n  <HEADER>\n")
+	if header.Preamble != "" {
+		fmt.Fprintf(w, "    <!-- %s -->\n", xmlCommentEscape(header.Preamble))
+	}
+	for _, field := range headerFields(header) {
+		if programid, fieldname, ok := splitAppFieldName(field[0]); ok {
+			fmt.Fprintf(w, "    <APP PROGRAMID=\"%s\" FIELDNAME=\"%s\">",
+				xmlAttrEscape(programid), xmlAttrEscape(fieldname))
+			xml.EscapeText(w, []byte(field[1]))
+			w.WriteString("</APP>\n")
+		} else {
+			writer.writeElement(strings.ToUpper(field[0]), field[1])
+		}
+	}
+	for _, def := range header.UserDefs {
+		fmt.Fprintf(w, "    <USERDEF FIELDID=\"%d\"", def.ID)
+		if def.TypeCode != 0 {
+			fmt.Fprintf(w, " TYPE=\"%c\"", def.TypeCode)
+		}
+		if def.Spec != "" {
+			attr := "ENUM"
+			if strings.Contains(def.Spec, ":") {
+				attr = "RANGE"
+			}
+			fmt.Fprintf(w, " %s=\"%s\"", attr, xmlAttrEscape(def.Spec))
+		}
+		w.WriteString(">")
+		xml.EscapeText(w, []byte(def.Name))
+		w.WriteString("</USERDEF>\n")
+	}
+	w.WriteString("  </HEADER>\n  <RECORDS>\n")
+}
+
+// Write a header element
+func (writer *adxWriter) writeElement(name, value string) {
+	fmt.Fprintf(writer.writer, "    <%s>", name)
+	xml.EscapeText(writer.writer, []byte(value))
+	fmt.Fprintf(writer.writer, "</%s>\n", name)
+}
+
+// Write a record field as a standard, APP or USERDEF element.  Custom
+// fields not declared in the header are written as APP fields of the
+// writer's program id.
+func (writer *adxWriter) writeField(name, value string, typecode byte) {
+	w := writer.writer
+	var tag string
+	if !isStandardADIFField(name) && writer.pending == nil && !writer.declared[name] {
+		if _, _, ok := splitAppFieldName(name); !ok {
+			name = "APP_" + writer.programID() + "_" + name
+		}
+	}
+	if programid, fieldname, ok := splitAppFieldName(name); ok {
+		tag = "APP"
+		fmt.Fprintf(w, "      <APP PROGRAMID=\"%s\" FIELDNAME=\"%s\"",
+			xmlAttrEscape(programid), xmlAttrEscape(fieldname))
+		if typecode != 0 {
+			fmt.Fprintf(w, " TYPE=\"%c\"", typecode)
+		}
+		w.WriteString(">")
+	} else if isStandardADIFField(name) {
+		tag = strings.ToUpper(name)
+		fmt.Fprintf(w, "      <%s>", tag)
+	} else {
+		tag = "USERDEF"
+		fmt.Fprintf(w, "      <USERDEF FIELDNAME=\"%s\">", xmlAttrEscape(name))
+	}
+	xml.EscapeText(w, []byte(value))
+	fmt.Fprintf(w, "</%s>\n", tag)
+}
+
+// Program id of the writer, for APP fields
+func (writer *adxWriter) programID() string {
+	if writer.config.programID != "" {
+		return writer.config.programID
+	}
+	return defaultProgramID
+}
+
+// Split an application-defined field name into program id and field name
+func splitAppFieldName(name string) (string, string, bool) {
+	if len(name) < 4 || !strings.EqualFold(name[:4], "app_") {
+		return "", "", false
+	}
+	parts := strings.SplitN(name[4:], "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Make text safe for an XML comment, which may not contain "--" or end
+// with "-"
+func xmlCommentEscape(s string) string {
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "- -")
+	}
+	if strings.HasSuffix(s, "-") {
+		s += " "
+	}
+	return s
+}
+
+func xmlAttrEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,220 @@
+package adifparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestADXWriterRoundTrip(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+	record.SetValue("qso_date", "20200101")
+	record.SetValue("comment", "Tom & Jerry <test>")
+	record.SetValue("sweatersize", "M")
+	record.SetTypedValue("app_monolog_compression", "off", 'S')
+
+	var buf bytes.Buffer
+	writer := NewADXWriter(&buf)
+	if err := writer.SetComment("Test -- export"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.SetComment("Too late"); err != OutputStarted {
+		t.Fatalf("Expected %v, got %v", OutputStarted, err)
+	}
+	// Flushing doesn't end the document
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "</ADX>") {
+		t.Fatalf("Document closed by Flush:\n%s", buf.String())
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteRecord(record); err != OutputFinished {
+		t.Fatalf("Expected %v, got %v", OutputFinished, err)
+	}
+
+	out := buf.String()
+	for _, expected := range []string{
+		"<!-- Test - - export -->",
+		"<ADIF_VER>" + ADIFVersion + "</ADIF_VER>",
+		"<CALL>W1AW</CALL>",
+		// Undeclared custom fields are written as APP fields
+		"<APP PROGRAMID=\"adifparser\" FIELDNAME=\"sweatersize\">M</APP>",
+		"<APP PROGRAMID=\"monolog\" FIELDNAME=\"compression\" TYPE=\"S\">off</APP>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+	// Standard fields follow ADIFfieldOrder
+	if strings.Index(out, "<CALL>") > strings.Index(out, "<QSO_DATE>") {
+		t.Fatalf("Fields out of order:\n%s", out)
+	}
+
+	reader := NewADXReader(&buf)
+	if reader.Header().Preamble != "Test - - export" {
+		t.Fatalf("Unexpected preamble %q", reader.Header().Preamble)
+	}
+	got, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.RenameField("sweatersize", "app_adifparser_sweatersize")
+	for _, field := range record.GetFields() {
+		expected, _ := record.GetValue(field)
+		if v, err := got.GetValue(field); err != nil || v != expected {
+			t.Fatalf("Expected %s to be %q, got %q (%v)", field, expected, v, err)
+		}
+	}
+	if _, err := reader.ReadRecord(); err != io.EOF {
+		t.Fatalf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestADXWriterComment(t *testing.T) {
+	for _, preamble := range []string{"a---b", "a----", "-", "x -- y-"} {
+		var buf bytes.Buffer
+		writer := NewADXWriter(&buf)
+		writer.SetComment(preamble)
+		writer.Close()
+		dec := xml.NewDecoder(&buf)
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%q: %v", preamble, err)
+			}
+		}
+	}
+}
+
+func TestADXWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADXWriter(&buf)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewADXReader(&buf)
+	if _, err := reader.ReadRecord(); err != io.EOF {
+		t.Fatalf("Expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestADXWriteHeader(t *testing.T) {
+	header := ADIFHeader{
+		ProgramID: "monolog",
+		UserDefs: []UserDef{
+			{ID: 1, Name: "SweaterSize", TypeCode: 'E', Spec: "{S,M,L}"},
+			{ID: 2, Name: "ShoeSize", TypeCode: 'N', Spec: "{5:20}"},
+		},
+		AppFields: map[string]string{"app_lotw_numrec": "1"},
+	}
+	var buf bytes.Buffer
+	writer := NewADXWriter(&buf)
+	if err := writer.WriteHeader(header); err != nil {
+		t.Fatal(err)
+	}
+	record := NewADIFRecord()
+	record.SetValue("sweatersize", "M")
+	writer.WriteRecord(record)
+	writer.Close()
+	out := buf.String()
+	for _, expected := range []string{
+		"<USERDEF FIELDID=\"1\" TYPE=\"E\" ENUM=\"{S,M,L}\">SweaterSize</USERDEF>",
+		// Declared fields are written as USERDEF fields
+		"<USERDEF FIELDNAME=\"sweatersize\">M</USERDEF>",
+		"<USERDEF FIELDID=\"2\" TYPE=\"N\" RANGE=\"{5:20}\">ShoeSize</USERDEF>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+	got := NewADXReader(&buf).Header()
+	if got.ProgramID != "monolog" || got.Version != ADIFVersion {
+		t.Fatalf("Unexpected header %+v", got)
+	}
+	if len(got.UserDefs) != 2 || got.UserDefs[1].Spec != "{5:20}" {
+		t.Fatalf("Unexpected userdefs %+v", got.UserDefs)
+	}
+	if got.AppFields["app_lotw_numrec"] != "1" {
+		t.Fatalf("Unexpected app fields %+v", got.AppFields)
+	}
+}
+
+func TestADXWriterIntl(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewADXWriter(&buf)
+	record := NewADIFRecord()
+	record.SetValue("name_intl", "東京")
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	bad := NewADIFRecord()
+	bad.SetValue("name_intl", "AB\xedD")
+	if err := writer.WriteRecord(bad); !errors.Is(err, InvalidUTF8) {
+		t.Fatalf("Expected %v, got %v", InvalidUTF8, err)
+	}
+	writer.Close()
+	got, err := NewADXReader(&buf).ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.GetValue("name_intl"); v != "東京" {
+		t.Fatalf("Unexpected name_intl %q", v)
+	}
+}
+
+func TestADXWriteUserDefs(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("call", "W1AW")
+	record.SetValue("sweatersize", "M")
+
+	var buf bytes.Buffer
+	writer := NewADXWriter(&buf, WithOutputUserDefs(0))
+	if err := writer.WriteRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader := NewADXReader(&buf)
+	got, err := reader.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.GetValue("sweatersize"); v != "M" {
+		t.Fatalf("Unexpected record %v", got)
+	}
+	defs := reader.Header().UserDefs
+	if len(defs) != 1 || defs[0] != (UserDef{ID: 1, Name: "sweatersize", TypeCode: 'S'}) {
+		t.Fatalf("Unexpected declarations %+v", defs)
+	}
+
+	// Fields first written after the header is released by the limit
+	// become APP fields
+	buf.Reset()
+	writer = NewADXWriter(&buf, WithOutputUserDefs(10))
+	writer.WriteRecord(record)
+	later := NewADIFRecord()
+	later.SetValue("shoesize", "9")
+	writer.WriteRecord(later)
+	writer.Close()
+	out := buf.String()
+	for _, expected := range []string{
+		"<USERDEF FIELDNAME=\"sweatersize\">M</USERDEF>",
+		"<APP PROGRAMID=\"adifparser\" FIELDNAME=\"shoesize\">9</APP>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in output:\n%s", expected, out)
+		}
+	}
+}
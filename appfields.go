@@ -0,0 +1,103 @@
+package adifparser
+
+import (
+	"strings"
+)
+
+// An application-defined field, APP_<ProgramID>_<Name>
+type AppField struct {
+	// Program id, in the case it was read or set with
+	ProgramID string
+	// Field name within the program
+	Name string
+	// ADIF data type indicator (0 if none)
+	TypeCode byte
+	Value    string
+}
+
+// Get the application-defined fields of a program (ignoring case), or of
+// all programs if programid is empty
+func (r *baseADIFRecord) AppFields(programid string) []AppField {
+	var fields []AppField
+	for _, name := range sortFields(r.GetFields()) {
+		if field, ok := r.appField(name); ok &&
+			(programid == "" || strings.EqualFold(field.ProgramID, programid)) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Get an application-defined field, ignoring case
+func (r *baseADIFRecord) GetAppField(programid, name string) (AppField, error) {
+	if field, ok := r.appField(strings.ToLower("app_" + programid + "_" + name)); ok {
+		return field, nil
+	}
+	return AppField{}, NoSuchField
+}
+
+// Set an application-defined field.  The program id may not contain "_".
+func (r *baseADIFRecord) SetAppField(field AppField) error {
+	if field.ProgramID == "" || field.Name == "" || strings.Contains(field.ProgramID, "_") {
+		return InvalidField
+	}
+	cased := "APP_" + field.ProgramID + "_" + field.Name
+	name := strings.ToLower(cased)
+	if field.TypeCode == 0 {
+		r.SetValue(name, field.Value)
+	} else {
+		r.SetTypedValue(name, field.Value, field.TypeCode)
+	}
+	r.setAppName(name, cased)
+	return nil
+}
+
+// Parse an application-defined field of the record
+func (r *baseADIFRecord) appField(name string) (AppField, bool) {
+	value, ok := r.values[name]
+	if !ok {
+		return AppField{}, false
+	}
+	programid, fieldname, ok := splitAppFieldName(outputName(r, name))
+	if !ok {
+		return AppField{}, false
+	}
+	return AppField{programid, fieldname, r.types[name], value}, true
+}
+
+// Remember the case of an application-defined field name
+func (r *baseADIFRecord) setAppName(name, cased string) {
+	r.ownAppNames()
+	if cased == name {
+		delete(r.appNames, name)
+		return
+	}
+	if r.appNames == nil {
+		r.appNames = make(map[string]string)
+	}
+	r.appNames[name] = cased
+}
+
+// Copy a shared table of field name cases before it is changed
+func (r *baseADIFRecord) ownAppNames() {
+	if !r.sharedAppNames {
+		return
+	}
+	r.sharedAppNames = false
+	names := make(map[string]string, len(r.appNames))
+	for name, cased := range r.appNames {
+		names[name] = cased
+	}
+	r.appNames = names
+}
+
+// Name of a field as it is written: application-defined fields keep the
+// case they were read or set with
+func outputName(r ADIFRecord, name string) string {
+	if br, ok := r.(*baseADIFRecord); ok {
+		if cased, ok := br.appNames[name]; ok {
+			return cased
+		}
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+package adifparser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// UTF-8 byte order mark
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Number of bytes examined when detecting the input format
+const sniffLength = 512
+
+// Create an ADIFReader for either ADI or ADX input, detected from the
+// start of the stream.  A byte order mark is discarded, but still counted
+// in record offsets.
+func NewAutoReader(r io.Reader, opts ...ReaderOption) ADIFReader {
+	br := bufio.NewReader(r)
+	bom := skipBOM(br)
+	if isADX(br) {
+		reader := NewADXReader(br, opts...)
+		reader.base = bom
+		return reader
+	}
+	reader := NewADIFReader(br, opts...)
+	reader.src.n += bom
+	reader.lineStart += bom
+	return reader
+}
+
+// Discard a byte order mark, returning its length
+func skipBOM(br *bufio.Reader) int64 {
+	if start, _ := br.Peek(len(utf8BOM)); bytes.Equal(start, utf8BOM) {
+		br.Discard(len(utf8BOM))
+		return int64(len(utf8BOM))
+	}
+	return 0
+}
+
+// Check whether the stream starts with an XML declaration or ADX element,
+// after optional whitespace
+func isADX(br *bufio.Reader) bool {
+	// Peek returns whatever is available if the input is short
+	start, _ := br.Peek(sniffLength)
+	start = bytes.TrimLeft(start, " \t\r\n")
+	return bytes.HasPrefix(start, []byte("<?xml")) ||
+		bytes.HasPrefix(bStrictToLower(start), []byte("<adx"))
+}
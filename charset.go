@@ -0,0 +1,152 @@
+package adifparser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Character set of ADI field values
+type Charset int
+
+const (
+	// Values are passed through unchanged
+	CharsetRaw Charset = iota
+	// UTF-8; invalid sequences are replaced with U+FFFD
+	CharsetUTF8
+	// ISO-8859-1 (Latin-1)
+	CharsetISO88591
+	// Windows-1252
+	CharsetWindows1252
+	// Each value is UTF-8 if it is valid UTF-8, otherwise Windows-1252
+	// (reading only)
+	CharsetAuto
+)
+
+var UnrepresentableCharacter = errors.New("Character cannot be represented in the output character set.")
+
+// Characters 0x80 to 0x9F in Windows-1252.  Unassigned bytes map to the
+// C1 control with the same value.
+var windows1252High = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// Reverse of windows1252High
+var windows1252Encode map[rune]byte
+
+func init() {
+	windows1252Encode = make(map[rune]byte, len(windows1252High))
+	for i, r := range windows1252High {
+		windows1252Encode[r] = byte(0x80 + i)
+	}
+}
+
+// Decode bytes in the character set to a UTF-8 string
+func (cs Charset) decode(b []byte) string {
+	switch cs {
+	case CharsetUTF8:
+		return strings.ToValidUTF8(string(b), "�")
+	case CharsetISO88591, CharsetWindows1252:
+		return decodeSingleByte(b, cs == CharsetWindows1252)
+	case CharsetAuto:
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return decodeSingleByte(b, true)
+	}
+	return string(b)
+}
+
+func decodeSingleByte(b []byte, windows bool) string {
+	if !hasNonASCII(string(b)) {
+		return string(b)
+	}
+	var s strings.Builder
+	s.Grow(len(b) * 2)
+	for _, c := range b {
+		switch {
+		case c < 0x80:
+			s.WriteByte(c)
+		case c < 0xA0 && windows:
+			s.WriteRune(windows1252High[c-0x80])
+		default:
+			s.WriteRune(rune(c))
+		}
+	}
+	return s.String()
+}
+
+// Encode a UTF-8 string in the character set
+func (cs Charset) encode(s string) (string, error) {
+	if cs != CharsetISO88591 && cs != CharsetWindows1252 {
+		return s, nil
+	}
+	if !hasNonASCII(s) {
+		return s, nil
+	}
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			b = append(b, byte(r))
+		case cs == CharsetWindows1252 && windows1252Encode[r] != 0:
+			b = append(b, windows1252Encode[r])
+		case r <= 0xFF && (cs == CharsetISO88591 || r >= 0xA0):
+			b = append(b, byte(r))
+		default:
+			return "", UnrepresentableCharacter
+		}
+	}
+	return string(b), nil
+}
+
+// Look up a character set by its IANA name
+func charsetByName(name string) (Charset, bool) {
+	switch strings.ToLower(name) {
+	case "utf-8", "utf8":
+		return CharsetUTF8, true
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+		return CharsetISO88591, true
+	case "windows-1252", "cp1252":
+		return CharsetWindows1252, true
+	}
+	return CharsetRaw, false
+}
+
+// Reader transcoding a single-byte character set to UTF-8, for the XML
+// decoder
+type charsetReader struct {
+	r   io.Reader
+	cs  Charset
+	buf []byte
+}
+
+func (cr *charsetReader) Read(p []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		raw := make([]byte, len(p)/3+1)
+		n, err := cr.r.Read(raw)
+		cr.buf = append(cr.buf, cr.cs.decode(raw[:n])...)
+		if err != nil && len(cr.buf) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+// CharsetReader for xml.Decoder supporting the single-byte character sets
+func xmlCharsetReader(label string, input io.Reader) (io.Reader, error) {
+	cs, ok := charsetByName(label)
+	if !ok {
+		return nil, errors.New("Unsupported character set " + label + ".")
+	}
+	if cs == CharsetUTF8 {
+		return input, nil
+	}
+	return &charsetReader{r: input, cs: cs}, nil
+}
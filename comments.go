@@ -0,0 +1,72 @@
+package adifparser
+
+import (
+	"errors"
+	"strings"
+)
+
+var InvalidComment = errors.New("Comment contains '<'.")
+
+// Free text found between the tags of an ADI file
+type Comment struct {
+	// The field the text follows, or "" if it comes before the first field
+	Field string
+	// The text, without surrounding whitespace
+	Text string
+}
+
+// Get the comments of a record, in the order they were read
+func (r *baseADIFRecord) Comments() []Comment {
+	return r.comments
+}
+
+// Replace the comments of a record
+func (r *baseADIFRecord) SetComments(comments []Comment) {
+	r.comments = comments
+}
+
+// Get the comments of the header, in the order they were read
+func (h *ADIFHeader) Comments() []Comment {
+	return h.comments
+}
+
+// Replace the comments of the header
+func (h *ADIFHeader) SetComments(comments []Comment) {
+	h.comments = comments
+}
+
+// Check that comments can be written without being read back as tags
+func checkComments(comments []Comment) error {
+	for _, c := range comments {
+		if strings.IndexByte(c.Text, '<') != -1 {
+			return InvalidComment
+		}
+	}
+	return nil
+}
+
+// Text of the comments following a field, each on the rest of the line
+func commentsAfter(comments []Comment, field string) string {
+	var text strings.Builder
+	for _, c := range comments {
+		if c.Field == field {
+			text.WriteString(" " + c.Text + "\n")
+		}
+	}
+	return text.String()
+}
+
+// Text of the comments not following any of the fields, one per line
+func otherComments(comments []Comment, fields []string) string {
+	var text strings.Builder
+	for _, c := range comments {
+		found := false
+		for _, name := range fields {
+			found = found || c.Field == name
+		}
+		if !found {
+			text.WriteString(c.Text + "\n")
+		}
+	}
+	return text.String()
+}
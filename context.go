@@ -0,0 +1,62 @@
+package adifparser
+
+import (
+	"context"
+	"io"
+)
+
+// Reader that abandons blocked reads when its context is done.  The read
+// is completed in the background and its data is returned by the next
+// Read.
+type contextReader struct {
+	r   io.Reader
+	ctx context.Context
+	// Result of a read still in progress when the context was done
+	pending chan readResult
+	// Data read in the background not yet returned
+	leftover []byte
+}
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+func newContextReader(r io.Reader) *contextReader {
+	return &contextReader{r: r, ctx: context.Background()}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if len(cr.leftover) > 0 {
+		n := copy(p, cr.leftover)
+		cr.leftover = cr.leftover[n:]
+		return n, nil
+	}
+	if cr.pending == nil {
+		if cr.ctx.Done() == nil {
+			return cr.r.Read(p)
+		}
+		if err := cr.ctx.Err(); err != nil {
+			return 0, err
+		}
+		pending := make(chan readResult, 1)
+		buf := make([]byte, len(p))
+		go func() {
+			n, err := cr.r.Read(buf)
+			pending <- readResult{buf[:n], err}
+		}()
+		cr.pending = pending
+	}
+	select {
+	case res := <-cr.pending:
+		cr.pending = nil
+		n := copy(p, res.data)
+		cr.leftover = res.data[n:]
+		if len(cr.leftover) > 0 {
+			return n, nil
+		}
+		return n, res.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package adifparser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Policy for the order in which the fields of a record are written.  It
+// returns the names of all of the record's fields.
+type FieldOrder func(r ADIFRecord) []string
+
+// Standard fields in the order of ADIFfieldOrder, then other fields in
+// alphabetical order.  This is the default.
+func SpecOrder(r ADIFRecord) []string {
+	return sortFields(r.GetFields())
+}
+
+// Fields in the order they were read or first set.  Records that don't
+// keep track of it are written in SpecOrder.
+func InputOrder(r ADIFRecord) []string {
+	if br, ok := r.(*baseADIFRecord); ok {
+		return append([]string(nil), br.order...)
+	}
+	return SpecOrder(r)
+}
+
+// The given fields first, in the given order, then the others in
+// SpecOrder
+func ColumnOrder(columns ...string) FieldOrder {
+	lower := make([]string, len(columns))
+	for i, column := range columns {
+		lower[i] = strings.ToLower(column)
+	}
+	return func(r ADIFRecord) []string {
+		names := make([]string, 0, len(lower))
+		listed := make(map[string]bool, len(lower))
+		for _, name := range lower {
+			if _, err := r.GetValue(name); err == nil && !listed[name] {
+				names = append(names, name)
+			}
+			listed[name] = true
+		}
+		for _, name := range SpecOrder(r) {
+			if !listed[name] {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+}
+
+// Order field names: standard fields in ADIFfieldOrder, then custom fields
+// in alphabetical order
+func sortFields(names []string) []string {
+	present := make(map[string]bool, len(names))
+	for _, n := range names {
+		present[n] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for _, n := range ADIFfieldOrder {
+		if present[n] {
+			sorted = append(sorted, n)
+		}
+	}
+	// Handle custom fields
+	custom := len(sorted)
+	for _, n := range names {
+		if !isStandardADIFField(n) {
+			sorted = append(sorted, n)
+		}
+	}
+	sort.Strings(sorted[custom:])
+	return sorted
+}
@@ -1,3 +1,3 @@
 module github.com/Matir/adifparser
 
-go 1.11
+go 1.23
@@ -0,0 +1,183 @@
+package adifparser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Errors
+var RecordOutOfRange = errors.New("Record number out of range.")
+var InvalidIndex = errors.New("Invalid index file.")
+var UnsupportedFormat = errors.New("Only ADI input can be indexed.")
+
+// First line of a saved index
+const indexMagic = "adifindex"
+const indexVersion = "1"
+
+// Location of a record in the input
+type IndexEntry struct {
+	Offset int64
+	Length int64
+}
+
+// Index of the records in an ADI file, for reading them in any order
+type ADIFIndex struct {
+	// Location of each record, by record number (from 0)
+	Records []IndexEntry
+	// Names of the secondary keys (lowercase field names)
+	Keys []string
+	// Key values of each record, in the order of Keys
+	values [][]string
+	// Record numbers by key and uppercase value
+	lookup map[string]map[string][]int
+}
+
+// Build an index by reading all records, with secondary keys for the
+// given fields.  The reader must read ADI, which ReadRecordAt parses.
+func BuildIndex(r ADIFReader, keys ...string) (*ADIFIndex, error) {
+	if !isADIReader(r) {
+		return nil, UnsupportedFormat
+	}
+	idx := newADIFIndex(keys)
+	for {
+		record, err := r.ReadRecord()
+		if err == io.EOF {
+			return idx, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, len(idx.Keys))
+		for i, key := range idx.Keys {
+			values[i], _ = record.GetValue(key)
+		}
+		idx.add(IndexEntry{r.RecordOffset(), r.RecordLength()}, values)
+	}
+}
+
+// Whether a reader reads ADI input
+func isADIReader(r ADIFReader) bool {
+	for {
+		switch reader := r.(type) {
+		case *adxReader:
+			return false
+		case *dedupeADIFReader:
+			r = reader.ADIFReader
+		default:
+			return true
+		}
+	}
+}
+
+func newADIFIndex(keys []string) *ADIFIndex {
+	idx := &ADIFIndex{}
+	idx.Keys = make([]string, len(keys))
+	idx.lookup = make(map[string]map[string][]int)
+	for i, key := range keys {
+		idx.Keys[i] = strings.ToLower(key)
+		idx.lookup[idx.Keys[i]] = make(map[string][]int)
+	}
+	return idx
+}
+
+func (idx *ADIFIndex) add(entry IndexEntry, values []string) {
+	n := len(idx.Records)
+	idx.Records = append(idx.Records, entry)
+	idx.values = append(idx.values, values)
+	for i, key := range idx.Keys {
+		if values[i] == "" {
+			continue
+		}
+		value := strings.ToUpper(values[i])
+		idx.lookup[key][value] = append(idx.lookup[key][value], n)
+	}
+}
+
+// Number of records in the index
+func (idx *ADIFIndex) Len() int {
+	return len(idx.Records)
+}
+
+// Numbers of the records whose key field has the value, ignoring case
+func (idx *ADIFIndex) Lookup(key, value string) []int {
+	return idx.lookup[strings.ToLower(key)][strings.ToUpper(value)]
+}
+
+// Read record n from the indexed input
+func (idx *ADIFIndex) ReadRecordAt(r io.ReaderAt, n int, opts ...ReaderOption) (ADIFRecord, error) {
+	if n < 0 || n >= len(idx.Records) {
+		return nil, RecordOutOfRange
+	}
+	entry := idx.Records[n]
+	reader := NewADIFReader(io.NewSectionReader(r, entry.Offset, entry.Length), opts...)
+	reader.headerRead = true
+	record, err := reader.ReadRecord()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return record, err
+}
+
+// Save the index.  The format is tab-separated text: a line naming the
+// keys, then the offset, length and key values of each record.
+func (idx *ADIFIndex) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	out := csv.NewWriter(cw)
+	out.Comma = '\t'
+	out.Write(append([]string{indexMagic, indexVersion}, idx.Keys...))
+	row := make([]string, 2+len(idx.Keys))
+	for n, entry := range idx.Records {
+		row[0] = strconv.FormatInt(entry.Offset, 10)
+		row[1] = strconv.FormatInt(entry.Length, 10)
+		copy(row[2:], idx.values[n])
+		if err := out.Write(row); err != nil {
+			return cw.n, err
+		}
+	}
+	out.Flush()
+	return cw.n, out.Error()
+}
+
+// Load an index saved with WriteTo
+func ReadIndex(r io.Reader) (*ADIFIndex, error) {
+	in := csv.NewReader(bufio.NewReader(r))
+	in.Comma = '\t'
+	in.ReuseRecord = true
+	row, err := in.Read()
+	if err != nil || len(row) < 2 || row[0] != indexMagic || row[1] != indexVersion {
+		return nil, InvalidIndex
+	}
+	idx := newADIFIndex(row[2:])
+	for {
+		row, err := in.Read()
+		if err == io.EOF {
+			return idx, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", InvalidIndex, err)
+		}
+		offset, err1 := strconv.ParseInt(row[0], 10, 64)
+		length, err2 := strconv.ParseInt(row[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%w: line %d", InvalidIndex, len(idx.Records)+2)
+		}
+		idx.add(IndexEntry{offset, length}, append([]string(nil), row[2:]...))
+	}
+}
+
+// Writer that counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
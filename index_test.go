@@ -0,0 +1,139 @@
+package adifparser
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	input, err := os.ReadFile("testdata/lotw.adi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := BuildIndex(NewADIFReader(bytes.NewReader(input)), "CALL", "qso_date")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every record can be read back from its location
+	reader := NewADIFReader(bytes.NewReader(input))
+	for n := 0; ; n++ {
+		expected, err := reader.ReadRecord()
+		if err != nil {
+			if n != idx.Len() {
+				t.Fatalf("Index has %d records, file has %d", idx.Len(), n)
+			}
+			break
+		}
+		got, err := idx.ReadRecordAt(bytes.NewReader(input), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.(*baseADIFRecord).values, expected.(*baseADIFRecord).values) {
+			t.Fatalf("Record %d: expected %s, got %s", n, expected.ToString(), got.ToString())
+		}
+	}
+
+	matches := idx.Lookup("call", "nc7l")
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 records for NC7L, got %v", matches)
+	}
+	for _, n := range matches {
+		record, _ := idx.ReadRecordAt(bytes.NewReader(input), n)
+		if call, _ := record.GetValue("call"); call != "NC7L" {
+			t.Fatalf("Record %d has call %q", n, call)
+		}
+	}
+	if _, err := idx.ReadRecordAt(bytes.NewReader(input), idx.Len()); !errors.Is(err, RecordOutOfRange) {
+		t.Fatalf("Expected %v, got %v", RecordOutOfRange, err)
+	}
+
+	// Save and load the index
+	var buf bytes.Buffer
+	n, err := idx.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	loaded, err := ReadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, idx) {
+		t.Fatal("Loaded index differs from the saved one")
+	}
+
+	if _, err := ReadIndex(bytes.NewReader([]byte("<call:4>W1AW<eor>"))); !errors.Is(err, InvalidIndex) {
+		t.Fatalf("Expected %v, got %v", InvalidIndex, err)
+	}
+}
+
+func TestIndexFormat(t *testing.T) {
+	adx := "<ADX><HEADER></HEADER><RECORDS><RECORD><CALL>W1AW</CALL></RECORD></RECORDS></ADX>"
+	for _, reader := range []ADIFReader{
+		NewADXReader(strings.NewReader(adx)),
+		NewDedupeReader(NewAutoReader(strings.NewReader(adx))),
+	} {
+		if _, err := BuildIndex(reader); !errors.Is(err, UnsupportedFormat) {
+			t.Fatalf("Expected %v, got %v", UnsupportedFormat, err)
+		}
+	}
+
+	// Offsets count a byte order mark
+	input := "\xef\xbb\xbf<eoh>\n<call:4>W1AW<eor>\n"
+	idx, err := BuildIndex(NewAutoReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := idx.ReadRecordAt(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call, _ := record.GetValue("call"); call != "W1AW" {
+		t.Fatalf("Unexpected record %s", record.ToString())
+	}
+
+	if _, err := ReadIndex(strings.NewReader("adifindex\t1\nx\t17\n")); err == nil || err.Error() != "Invalid index file.: line 2" {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestRecordLength(t *testing.T) {
+	input := "Preamble\n<eoh>\n<call:4>W1AW<eor>\n  <CALL:5>KF4MD <BAND:3>20m <EOR>\n"
+	reader := NewADIFReader(bytes.NewReader([]byte(input)))
+	for _, expected := range []string{"<call:4>W1AW<eor>", "<CALL:5>KF4MD <BAND:3>20m <EOR>"} {
+		if _, err := reader.ReadRecord(); err != nil {
+			t.Fatal(err)
+		}
+		offset, length := reader.RecordOffset(), reader.RecordLength()
+		if got := input[offset : offset+length]; got != expected {
+			t.Fatalf("Expected %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestADXRecordLength(t *testing.T) {
+	input := "<ADX><HEADER></HEADER><RECORDS>\n<RECORD><CALL>W1AW</CALL></RECORD>\n</RECORDS></ADX>"
+	bom := "\xef\xbb\xbf" + input
+	for _, test := range []struct {
+		input  string
+		reader ADIFReader
+	}{
+		{input, NewADXReader(strings.NewReader(input))},
+		{bom, NewAutoReader(strings.NewReader(bom))},
+	} {
+		if _, err := test.reader.ReadRecord(); err != nil {
+			t.Fatal(err)
+		}
+		offset, length := test.reader.RecordOffset(), test.reader.RecordLength()
+		if got := test.input[offset : offset+length]; got != "<RECORD><CALL>W1AW</CALL></RECORD>" {
+			t.Fatalf("Unexpected record %q", got)
+		}
+	}
+}
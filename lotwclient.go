@@ -2,6 +2,7 @@ package adifparser
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -22,6 +23,8 @@ type lotwClientImpl struct {
 	password string
 	// HTTP state
 	httpResponse *http.Response
+	// Context for the request
+	ctx context.Context
 	// Temporary read buffer
 	buf []byte
 	// Options
@@ -34,7 +37,13 @@ type lotwClientImpl struct {
 
 // Create a new client
 func NewLOTWClient(username, password string) *lotwClientImpl {
+	return NewLOTWClientContext(context.Background(), username, password)
+}
+
+// Create a new client whose request is cancelled when ctx is done
+func NewLOTWClientContext(ctx context.Context, username, password string) *lotwClientImpl {
 	client := &lotwClientImpl{}
+	client.ctx = ctx
 	client.username = username
 	client.password = password
 	client.buf = make([]byte, 0, 1024)
@@ -50,6 +59,9 @@ func NewLOTWClient(username, password string) *lotwClientImpl {
 
 // Read from socket
 func (c *lotwClientImpl) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
 	if c.httpResponse == nil {
 		if err := c.open(); err != nil {
 			// TODO: better logging
@@ -97,7 +109,11 @@ func (c *lotwClientImpl) open() error {
 	requri, _ := url.Parse(LOTWAPI)
 	requri.RawQuery = makeQueryString(params)
 	adiflog.Printf("LOTW Requesting: %s\n", requri.String())
-	resp, err := http.Get(requri.String())
+	req, err := http.NewRequest("GET", requri.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(c.ctx))
 	if err != nil {
 		return err
 	}
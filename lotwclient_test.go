@@ -1,6 +1,7 @@
 package adifparser
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"testing"
@@ -59,3 +60,14 @@ func TestReadChunked(t *testing.T) {
 		t.Fatalf("Expected %v, got %v.\n", testString, buf)
 	}
 }
+
+func TestReadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewLOTWClientContext(ctx, "u", "p")
+	c.httpResponse = makeMockResponse("<call:4>W1AW<eor>")
+	cancel()
+	buf := make([]byte, 1024)
+	if _, err := c.Read(buf); err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/Matir/adifparser"
-	"io"
 	"os"
+	"os/signal"
 	"time"
 )
 
@@ -20,22 +21,28 @@ func main() {
 		return
 	}
 
-	client := adifparser.NewLOTWClient(*username, *password)
+	// Abort the download on interrupt
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	client := adifparser.NewLOTWClientContext(ctx, *username, *password)
 	reader := adifparser.NewADIFReader(client)
-	writer := adifparser.NewADIFWriter(os.Stdout)
-	defer writer.Flush()
+	// Copy values unchanged, including non-ASCII text in any field
+	writer := adifparser.NewADIFWriter(os.Stdout, adifparser.WithOutputCharset(adifparser.CharsetUTF8))
+	defer writer.Close()
 	defer client.Close()
 
 	t := time.Now().Format("2006/01/02 15:04:05")
 	writer.SetComment(fmt.Sprintf("Downloaded from LOTW at %s.", t))
 
-	for record, err := reader.ReadRecord(); record != nil; record, err = reader.ReadRecord() {
-		if err != nil {
-			if err != io.EOF {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
-			}
+	for result := range reader.Records(ctx) {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", result.Err)
+			return
+		}
+		if err := writer.WriteRecord(result.Record); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
 			return
 		}
-		writer.WriteRecord(record)
 	}
 }
@@ -0,0 +1,56 @@
+package adifparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// How Merge resolves a field present in both records
+type MergePolicy int
+
+const (
+	// Keep the record's value; empty values count as absent
+	MergeKeep MergePolicy = iota
+	// Replace the record's value with the other record's
+	MergeOverwrite
+	// Fail if the values differ, leaving the record unchanged
+	MergeStrict
+)
+
+var MergeConflict = errors.New("Conflicting field values.")
+var InvalidMergePolicy = errors.New("Unknown merge policy.")
+
+// Copy the fields of another record, with their types.  Fields present in
+// both records are resolved according to the policy.
+func (r *baseADIFRecord) Merge(other ADIFRecord, policy MergePolicy) error {
+	if policy < MergeKeep || policy > MergeStrict {
+		return InvalidMergePolicy
+	}
+	var names []string
+	for _, name := range other.Fields() {
+		value, _ := other.GetValue(name)
+		current, ok := r.values[name]
+		switch {
+		case !ok || current == value:
+		case policy == MergeKeep:
+			if current != "" {
+				continue
+			}
+		case policy == MergeStrict:
+			return fmt.Errorf("%s: %w", name, MergeConflict)
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		value, _ := other.GetValue(name)
+		if code := recordTypeCode(other, name); code != 0 {
+			r.SetTypedValue(name, value, code)
+		} else {
+			r.SetValue(name, value)
+		}
+		if cased := outputName(other, name); cased != name {
+			r.setAppName(name, cased)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package adifparser
+
+// Interpretation of the length in an ADI tag
+type LengthMode int
+
+const (
+	// The length is a count of bytes, as the ADIF specification requires
+	LengthBytes LengthMode = iota
+	// The length is a count of UTF-8 characters
+	LengthRunes
+	// The length is a count of bytes, unless the value contains UTF-8
+	// characters and counting characters is the only way to reach the
+	// next tag (reading only)
+	LengthAuto
+)
+
+// Reader configuration, set with ReaderOptions
+type readerConfig struct {
+	// Skip malformed records instead of failing
+	recover bool
+	// Called for each skipped record (may be nil)
+	onSkip func(SkippedRecord)
+	// Interpretation of value lengths
+	lengthMode LengthMode
+	// Character set of the input
+	charset Charset
+	// Keep the text of records and the header for writing them unchanged
+	lossless bool
+	// Keep text between tags as comments
+	comments bool
+	// Number of goroutines parsing chunks in the parallel reader
+	workers int
+	// Approximate size of the chunks read by the parallel reader
+	chunkSize int64
+}
+
+// Option for the reader constructors
+type ReaderOption func(*readerConfig)
+
+// A record skipped because it could not be parsed
+type SkippedRecord struct {
+	// Raw bytes of the record, up to and including the <eor> tag or
+	// </RECORD> element (nil for ADX input transcoded from a character set
+	// other than UTF-8)
+	Raw []byte
+	// The error that caused the record to be skipped
+	Err *ParseError
+}
+
+// Skip malformed records instead of returning an error.  The reader
+// resynchronises at the next <eor> and calls handler (if not nil) with the
+// skipped record.  In ADX input, records with invalid contents are
+// skipped, but XML syntax errors are still returned.
+func WithRecovery(handler func(SkippedRecord)) ReaderOption {
+	return func(c *readerConfig) {
+		c.recover = true
+		c.onSkip = handler
+	}
+}
+
+// Set how the length in ADI tags is interpreted
+func WithLengthMode(mode LengthMode) ReaderOption {
+	return func(c *readerConfig) {
+		c.lengthMode = mode
+	}
+}
+
+// Set the character set of ADI input; values are transcoded to UTF-8.
+// Lengths are always counted in the input's bytes, except in LengthRunes
+// and LengthAuto modes, which assume UTF-8 input.
+func WithCharset(cs Charset) ReaderOption {
+	return func(c *readerConfig) {
+		c.charset = cs
+	}
+}
+
+// Keep the exact text of the header and of each record of ADI input,
+// including the text between them.  ADIFWriter writes headers and records
+// that have not been changed exactly as they were read, and keeps the
+// field order, tag case and type indicators of changed records.
+func WithLossless() ReaderOption {
+	return func(c *readerConfig) {
+		c.lossless = true
+	}
+}
+
+// Keep free text between the tags of ADI input, such as the "// ..."
+// comments in LoTW reports, as comments of the record or header
+func WithComments() ReaderOption {
+	return func(c *readerConfig) {
+		c.comments = true
+	}
+}
+
+// Set the number of goroutines used by the parallel reader (by default
+// GOMAXPROCS)
+func WithWorkers(n int) ReaderOption {
+	return func(c *readerConfig) {
+		c.workers = n
+	}
+}
+
+// Set the approximate number of bytes the parallel reader hands to each
+// goroutine (by default 1 MiB).  Chunks always end at a record boundary.
+func WithChunkSize(n int64) ReaderOption {
+	return func(c *readerConfig) {
+		c.chunkSize = n
+	}
+}
+
+func newReaderConfig(opts []ReaderOption) readerConfig {
+	config := readerConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// Writer configuration, set with WriterOptions
+type writerConfig struct {
+	// How lengths are written in ADI tags
+	lengthMode LengthMode
+	// Character set of ADI output
+	charset Charset
+	// Order of the fields in each record
+	fieldOrder FieldOrder
+	// Write the comments of records and headers
+	comments bool
+	// Declare the custom fields written in the header, holding back at
+	// most userDefLimit bytes of records
+	userDefs     bool
+	userDefLimit int
+	// PROGRAMID written in headers, if not empty
+	programID string
+}
+
+// Option for the writer constructors
+type WriterOption func(*writerConfig)
+
+// Set how lengths are written in ADI tags (LengthBytes or LengthRunes)
+func WithOutputLengthMode(mode LengthMode) WriterOption {
+	return func(c *writerConfig) {
+		c.lengthMode = mode
+	}
+}
+
+// Set the character set of ADI output (CharsetUTF8, CharsetISO88591 or
+// CharsetWindows1252).  Non-ASCII characters are then allowed in any field;
+// with CharsetUTF8, values are written unchanged.
+func WithOutputCharset(cs Charset) WriterOption {
+	return func(c *writerConfig) {
+		c.charset = cs
+	}
+}
+
+// Write the comments of ADI records and headers after the fields they
+// follow
+func WithOutputComments() WriterOption {
+	return func(c *writerConfig) {
+		c.comments = true
+	}
+}
+
+// Declare the custom fields of the records in the header, as USERDEFn
+// fields.  The header and records are held back until the first Flush, or
+// until more than limit bytes of records are held (1 MiB if limit is not
+// positive).  Custom fields first written after the header are not
+// declared; ADX output writes them as APP fields.
+func WithOutputUserDefs(limit int) WriterOption {
+	return func(c *writerConfig) {
+		c.userDefs = true
+		c.userDefLimit = limit
+	}
+}
+
+// Set the PROGRAMID written in headers, replacing that of the header
+// given (by default adifparser)
+func WithOutputProgramID(programid string) WriterOption {
+	return func(c *writerConfig) {
+		c.programID = programid
+	}
+}
+
+// Set the order of the fields in each record (by default SpecOrder)
+func WithFieldOrder(order FieldOrder) WriterOption {
+	return func(c *writerConfig) {
+		c.fieldOrder = order
+	}
+}
+
+func newWriterConfig(opts []WriterOption) writerConfig {
+	config := writerConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.fieldOrder == nil {
+		config.fieldOrder = SpecOrder
+	}
+	return config
+}
@@ -0,0 +1,324 @@
+package adifparser
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"runtime"
+)
+
+// Default size of the chunks handed to each goroutine
+const defaultChunkSize = 1 << 20
+
+// Reader parsing an ADI file in chunks on several goroutines.  Records are
+// returned in the order they appear in the file.
+type parallelADIFReader struct {
+	// Parsed header
+	header *ADIFHeader
+	// USERDEF declarations of the header, by lowercase name
+	userDefs map[string]UserDef
+	// Results of each chunk, in file order
+	chunks chan chan chunkResult
+	// Result of the chunk being waited for
+	next chan chunkResult
+	// Records of the current chunk not yet returned
+	items []chunkItem
+	// Error ending the current chunk
+	err error
+	// Record count
+	records int
+	// Offset and length of the most recent record
+	recordOffset int64
+	recordLength int64
+	// Options
+	config readerConfig
+	// Closed to stop the goroutines
+	done   chan struct{}
+	closed bool
+}
+
+// A range of the input ending at a record boundary
+type chunk struct {
+	start, end int64
+	// Position of the start of the chunk
+	line      int
+	lineStart int64
+	// Records read and skipped before the chunk
+	records, skipped int
+	// Where to send the result
+	result chan chunkResult
+}
+
+// A record or skipped record in a chunk
+type chunkItem struct {
+	record  ADIFRecord
+	offset  int64
+	length  int64
+	skipped *SkippedRecord
+}
+
+type chunkResult struct {
+	items []chunkItem
+	// Error ending the chunk, or nil
+	err error
+}
+
+// Create a reader parsing the first size bytes of r on several goroutines.
+// Use Close to stop the goroutines if the records are not read to the end.
+func NewParallelADIFReader(r io.ReaderAt, size int64, opts ...ReaderOption) *parallelADIFReader {
+	reader := &parallelADIFReader{}
+	reader.config = newReaderConfig(opts)
+	if reader.config.workers < 1 {
+		reader.config.workers = runtime.GOMAXPROCS(0)
+	}
+	if reader.config.chunkSize < 1 {
+		reader.config.chunkSize = defaultChunkSize
+	}
+	reader.done = make(chan struct{})
+	reader.chunks = make(chan chan chunkResult, 2*reader.config.workers)
+
+	// The splitter finds record boundaries with its own reader, which
+	// also reads the header and parses any malformed records
+	scanConfig := reader.config
+	scanConfig.onSkip = nil
+	scanner := &baseADIFReader{config: scanConfig}
+	scanner.init(io.NewSectionReader(r, 0, size))
+	reader.header = scanner.Header()
+	reader.userDefs = scanner.userDefs
+	scanner.skim = true
+
+	jobs := make(chan chunk)
+	go reader.split(scanner, size, jobs)
+	for i := 0; i < reader.config.workers; i++ {
+		go reader.work(r, jobs)
+	}
+	return reader
+}
+
+// Divide the input into chunks, queueing them for the workers and their
+// results for ReadRecord
+func (ardr *parallelADIFReader) split(scanner *baseADIFReader, size int64, jobs chan<- chunk) {
+	defer close(ardr.chunks)
+	defer close(jobs)
+	for {
+		job := chunk{
+			start:     scanner.position(),
+			line:      scanner.line,
+			lineStart: scanner.lineStart,
+			records:   scanner.records,
+			skipped:   scanner.skipped,
+			result:    make(chan chunkResult, 1),
+		}
+		var err error
+		for err == nil && scanner.position()-job.start < ardr.config.chunkSize {
+			err = scanner.skipRecord()
+		}
+		job.end = scanner.position()
+		if err != nil && err != io.EOF {
+			// Let the worker find the error again, with the records
+			// before it
+			job.end = size
+		}
+		if job.end == job.start {
+			return
+		}
+		select {
+		case jobs <- job:
+		case <-ardr.done:
+			return
+		}
+		select {
+		case ardr.chunks <- job.result:
+		case <-ardr.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Skip to the end of the next record, reading only the tags and skipping
+// values by their length.  A malformed tag is left to ReadRecord, which
+// finds the error or, in recovery mode, skips the record.
+func (ardr *baseADIFReader) skipRecord() error {
+	for {
+		buf, err := ardr.peekBuffered()
+		if len(buf) == 0 {
+			return err
+		}
+		i := bytes.IndexByte(buf, '<')
+		if i == -1 {
+			ardr.consume(len(buf))
+			continue
+		}
+		ardr.consume(i)
+		buf, end, _ := ardr.peekTag()
+		if end == -1 {
+			_, err := ardr.ReadRecord()
+			return err
+		}
+		tag := buf[1:end]
+		parts, _, err := splitTag(tag)
+		if err != nil || parts.colons > 0 && parts.lengthEnd == parts.nameEnd+1 {
+			_, err := ardr.ReadRecord()
+			return err
+		}
+		ardr.consume(end + 1)
+		if parts.colons == 0 {
+			if bytes.EqualFold(tag, []byte("eor")) {
+				ardr.records++
+				if ardr.config.lossless {
+					ardr.readTrailingText()
+				}
+				return nil
+			}
+			continue
+		}
+		if err := ardr.skipValue(parts.length); err != nil {
+			return err
+		}
+	}
+}
+
+// Skip a field value of the given length
+func (ardr *baseADIFReader) skipValue(length int) error {
+	if ardr.config.lengthMode != LengthBytes {
+		_, err := ardr.readValue(length)
+		return err
+	}
+	for length > 0 {
+		buf, err := ardr.peekBuffered()
+		if len(buf) == 0 {
+			return err
+		}
+		n := min(len(buf), length)
+		ardr.consume(n)
+		length -= n
+	}
+	return nil
+}
+
+// Parse chunks
+func (ardr *parallelADIFReader) work(r io.ReaderAt, jobs <-chan chunk) {
+	for job := range jobs {
+		job.result <- ardr.parseChunk(r, job)
+	}
+}
+
+func (ardr *parallelADIFReader) parseChunk(r io.ReaderAt, job chunk) chunkResult {
+	var result chunkResult
+	config := ardr.config
+	if config.recover {
+		config.onSkip = func(skipped SkippedRecord) {
+			result.items = append(result.items, chunkItem{skipped: &skipped})
+		}
+	}
+	chunkReader := &baseADIFReader{config: config}
+	chunkReader.init(io.NewSectionReader(r, job.start, job.end-job.start))
+	// Positions and record numbers are those in the whole input
+	chunkReader.headerRead = true
+	chunkReader.src.n += job.start
+	chunkReader.line = job.line
+	chunkReader.lineStart = job.lineStart
+	chunkReader.records = job.records
+	chunkReader.skipped = job.skipped
+	chunkReader.userDefs = ardr.userDefs
+	for {
+		record, err := chunkReader.ReadRecord()
+		if err != nil {
+			if err != io.EOF {
+				result.err = err
+			}
+			return result
+		}
+		result.items = append(result.items, chunkItem{
+			record: record,
+			offset: chunkReader.RecordOffset(),
+			length: chunkReader.RecordLength(),
+		})
+	}
+}
+
+func (ardr *parallelADIFReader) ReadRecord() (ADIFRecord, error) {
+	return ardr.ReadRecordContext(context.Background())
+}
+
+func (ardr *parallelADIFReader) ReadRecordContext(ctx context.Context) (ADIFRecord, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(ardr.items) > 0 {
+			item := ardr.items[0]
+			ardr.items = ardr.items[1:]
+			if item.skipped != nil {
+				if ardr.config.onSkip != nil {
+					ardr.config.onSkip(*item.skipped)
+				}
+				continue
+			}
+			ardr.records++
+			ardr.recordOffset = item.offset
+			ardr.recordLength = item.length
+			return item.record, nil
+		}
+		if ardr.err != nil {
+			return nil, ardr.err
+		}
+		if ardr.next == nil {
+			select {
+			case next, ok := <-ardr.chunks:
+				if !ok {
+					return nil, io.EOF
+				}
+				ardr.next = next
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		select {
+		case result := <-ardr.next:
+			ardr.next = nil
+			ardr.items = result.items
+			ardr.err = result.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (ardr *parallelADIFReader) All() iter.Seq2[ADIFRecord, error] {
+	return readerSeq(ardr)
+}
+
+func (ardr *parallelADIFReader) Records(ctx context.Context) <-chan RecordResult {
+	return readerChan(ctx, ardr)
+}
+
+func (ardr *parallelADIFReader) RecordCount() int {
+	return ardr.records
+}
+
+func (ardr *parallelADIFReader) RecordOffset() int64 {
+	return ardr.recordOffset
+}
+
+func (ardr *parallelADIFReader) RecordLength() int64 {
+	return ardr.recordLength
+}
+
+func (ardr *parallelADIFReader) Header() *ADIFHeader {
+	return ardr.header
+}
+
+// Stop the goroutines.  Reading after Close returns io.EOF once the
+// chunks already parsed are used up.
+func (ardr *parallelADIFReader) Close() error {
+	if !ardr.closed {
+		ardr.closed = true
+		close(ardr.done)
+	}
+	return nil
+}
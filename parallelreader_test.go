@@ -0,0 +1,163 @@
+package adifparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type parallelResult struct {
+	values  []map[string]string
+	offsets []int64
+	lengths []int64
+	// Text of the records read in lossless mode
+	raw     []string
+	skipped []string
+	count   int
+	err     error
+}
+
+func readAll(reader ADIFReader, skipped *[]string) parallelResult {
+	var result parallelResult
+	for {
+		record, err := reader.ReadRecord()
+		if err != nil {
+			if err != io.EOF {
+				result.err = err
+			}
+			break
+		}
+		result.values = append(result.values, record.(*baseADIFRecord).values)
+		if source := record.(*baseADIFRecord).source; source != nil {
+			result.raw = append(result.raw, source.raw)
+		}
+		result.offsets = append(result.offsets, reader.RecordOffset())
+		result.lengths = append(result.lengths, reader.RecordLength())
+	}
+	result.count = reader.RecordCount()
+	if skipped != nil {
+		result.skipped = *skipped
+	}
+	return result
+}
+
+func compareParallel(t *testing.T, input []byte, recover bool, opts ...ReaderOption) {
+	for _, chunkSize := range []int64{1, 64, 1000, 1 << 20} {
+		var seqSkipped, parSkipped []string
+		seqOpts := append([]ReaderOption(nil), opts...)
+		parOpts := append([]ReaderOption(nil), opts...)
+		if recover {
+			seqOpts = append(seqOpts, WithRecovery(func(s SkippedRecord) {
+				seqSkipped = append(seqSkipped, string(s.Raw)+s.Err.Error())
+			}))
+			parOpts = append(parOpts, WithRecovery(func(s SkippedRecord) {
+				parSkipped = append(parSkipped, string(s.Raw)+s.Err.Error())
+			}))
+		}
+		parOpts = append(parOpts, WithWorkers(3), WithChunkSize(chunkSize))
+		sequential := NewADIFReader(bytes.NewReader(input), seqOpts...)
+		parallel := NewParallelADIFReader(bytes.NewReader(input), int64(len(input)), parOpts...)
+		expected := readAll(sequential, &seqSkipped)
+		got := readAll(parallel, &parSkipped)
+		parallel.Close()
+		if fmtErr(got.err) != fmtErr(expected.err) {
+			t.Fatalf("chunk size %d: expected error %v, got %v", chunkSize, expected.err, got.err)
+		}
+		got.err, expected.err = nil, nil
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("chunk size %d: results differ\nexpected %+v\ngot %+v", chunkSize, expected, got)
+		}
+		if !reflect.DeepEqual(parallel.Header(), sequential.Header()) {
+			t.Fatalf("chunk size %d: headers differ", chunkSize)
+		}
+	}
+}
+
+func fmtErr(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestParallelReader(t *testing.T) {
+	for _, name := range []string{"testdata/lotw.adi", "testdata/wsjtx.adi", "testdata/xlog.adi"} {
+		input, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareParallel(t, input, false)
+	}
+	compareParallel(t, benchmarkInput(500), false)
+
+	// "<eor>" inside a value must not split the record
+	input := []byte("<eoh>\n<call:4>W1AW<comment:12>a <eor> here<eor>\n<call:5>KF4MD<eor>\n")
+	compareParallel(t, input, false)
+	records := readAll(NewParallelADIFReader(bytes.NewReader(input), int64(len(input)), WithChunkSize(1)), nil)
+	if records.count != 2 || records.values[0]["comment"] != "a <eor> here" {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+
+	// Values are skipped according to the length mode, and text after a
+	// record stays with it in lossless mode
+	input = []byte("<eoh>\n<name:4>José<eor> after\n<name:3>Zoë<call:4>W1AW<eor>\n<comment:10>ééééé<eor><eor>\n")
+	compareParallel(t, input, false, WithLengthMode(LengthAuto))
+	compareParallel(t, input, false, WithLengthMode(LengthRunes), WithLossless())
+}
+
+func TestParallelReaderErrors(t *testing.T) {
+	input := []byte("<eoh>\n<call:4>W1AW<eor>\n<call:x>W2AW<eor>\n<call:4>N0CA<eor>\n" +
+		"<call:4>N1CA<freq:2:NN>14<eor>\n<call:4>N2CA<eor>\n")
+	compareParallel(t, input, false)
+	compareParallel(t, input, true)
+
+	parallel := NewParallelADIFReader(bytes.NewReader(input), int64(len(input)), WithChunkSize(1))
+	defer parallel.Close()
+	result := readAll(parallel, nil)
+	var perr *ParseError
+	if !errors.As(result.err, &perr) || perr.Line != 3 || perr.Record != 2 {
+		t.Fatalf("Unexpected error %v", result.err)
+	}
+}
+
+func TestParallelReaderClose(t *testing.T) {
+	input := benchmarkInput(1000)
+	parallel := NewParallelADIFReader(bytes.NewReader(input), int64(len(input)), WithChunkSize(100))
+	if _, err := parallel.ReadRecord(); err != nil {
+		t.Fatal(err)
+	}
+	parallel.Close()
+	for {
+		if _, err := parallel.ReadRecord(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.HasPrefix(parallel.Header().Preamble, "Benchmark") {
+		t.Fatalf("Unexpected preamble %q", parallel.Header().Preamble)
+	}
+}
+
+func BenchmarkParallelReader(b *testing.B) {
+	const records = 20000
+	input := benchmarkInput(records)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := NewParallelADIFReader(bytes.NewReader(input), int64(len(input)), WithChunkSize(64<<10))
+		for _, err := range reader.All() {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		if reader.RecordCount() != records {
+			b.Fatalf("Expected %d records, got %d", records, reader.RecordCount())
+		}
+	}
+}
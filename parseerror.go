@@ -0,0 +1,33 @@
+package adifparser
+
+import (
+	"fmt"
+)
+
+// Error parsing the input, with the position where it occurred
+type ParseError struct {
+	// Byte offset of the offending tag
+	Offset int64
+	// Line and column (in bytes) of the offending tag, starting at 1
+	Line   int
+	Column int
+	// Index of the record being read, starting at 1 (0 for the header)
+	Record int
+	// Text of the tag up to the problem
+	Tag string
+	// Underlying error (InvalidFieldLength, TypeCodeExceedOneByte, ...)
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Tag == "" {
+		return fmt.Sprintf("line %d, column %d (offset %d), record %d: %v",
+			e.Line, e.Column, e.Offset, e.Record, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %d (offset %d), record %d: %v in tag %q",
+		e.Line, e.Column, e.Offset, e.Record, e.Err, e.Tag)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,55 @@
+package adifparser
+
+import (
+	"time"
+)
+
+// Combine a Date and a Time field into a UTC timestamp
+func (r *baseADIFRecord) dateTime(datefield, timefield string) (time.Time, error) {
+	d, err := r.GetDate(datefield)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := r.GetTime(timefield)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(),
+		t.Hour(), t.Minute(), t.Second(), 0, time.UTC), nil
+}
+
+// Get the QSO start time from qso_date and time_on
+func (r *baseADIFRecord) QSOStart() (time.Time, error) {
+	return r.dateTime("qso_date", "time_on")
+}
+
+// Get the QSO end time from qso_date_off and time_off.  If qso_date_off is
+// missing, qso_date is used, moving to the next day for QSOs that cross
+// midnight.
+func (r *baseADIFRecord) QSOEnd() (time.Time, error) {
+	if _, ok := r.values["qso_date_off"]; ok {
+		return r.dateTime("qso_date_off", "time_off")
+	}
+	end, err := r.dateTime("qso_date", "time_off")
+	if err != nil {
+		return end, err
+	}
+	if start, err := r.QSOStart(); err == nil && end.Before(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, nil
+}
+
+// Set qso_date and time_on from a timestamp
+func (r *baseADIFRecord) SetQSOStart(t time.Time) {
+	t = t.UTC()
+	r.SetValue("qso_date", t.Format("20060102"))
+	r.SetValue("time_on", t.Format("150405"))
+}
+
+// Set qso_date_off and time_off from a timestamp
+func (r *baseADIFRecord) SetQSOEnd(t time.Time) {
+	t = t.UTC()
+	r.SetValue("qso_date_off", t.Format("20060102"))
+	r.SetValue("time_off", t.Format("150405"))
+}
@@ -0,0 +1,58 @@
+package adifparser
+
+import (
+	"context"
+	"io"
+	"iter"
+)
+
+// A record or error sent by ADIFReader.Records
+type RecordResult struct {
+	Record ADIFRecord
+	Err    error
+}
+
+// Iterate over the records of a reader.  The iteration ends at the end of
+// the input, or after yielding the first error.
+func readerSeq(r ADIFReader) iter.Seq2[ADIFRecord, error] {
+	return func(yield func(ADIFRecord, error) bool) {
+		for {
+			record, err := r.ReadRecord()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Read records on a goroutine and send them on the returned channel, which
+// is closed at the end of the input, after the first error, or when ctx is
+// done.
+func readerChan(ctx context.Context, r ADIFReader) <-chan RecordResult {
+	results := make(chan RecordResult)
+	go func() {
+		defer close(results)
+		for {
+			record, err := r.ReadRecordContext(ctx)
+			if err == io.EOF {
+				return
+			}
+			select {
+			case results <- RecordResult{record, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,222 @@
+package adifparser
+
+import (
+	"reflect"
+)
+
+// A field of a record as read in lossless mode
+type sourceField struct {
+	name     string
+	value    string
+	typecode byte
+	hasType  bool
+	// Position of the tag and value in the captured record
+	start, end int
+	// Name as written in the tag
+	tagName string
+	// The tag and value as read
+	raw string
+	// Text after the value, up to the next tag
+	after string
+}
+
+// The text of a record read in lossless mode
+type recordSource struct {
+	// Exact text of the record, from its first tag to the next record
+	raw string
+	// Text before the first field
+	lead   string
+	fields []sourceField
+	// The <eor> tag and the text after it
+	eor string
+	// Options the text was read with
+	options sourceOptions
+}
+
+// The text of a header read in lossless mode
+type headerSource struct {
+	raw string
+	// The header as read, to detect changes
+	parsed ADIFHeader
+	// Options the text was read with
+	options sourceOptions
+}
+
+// Reader options that decide how text is decoded
+type sourceOptions struct {
+	charset    Charset
+	lengthMode LengthMode
+}
+
+// Split a captured record into its fields and the text around them.
+// first and eor are the positions of the first tag and the <eor> tag.
+func newRecordSource(capture []byte, first, eor int, fields []sourceField, options sourceOptions) *recordSource {
+	source := &recordSource{options: options}
+	source.raw = string(capture[first:])
+	raw := source.raw
+	end := eor - first
+	if len(fields) > 0 {
+		end = fields[0].start - first
+	}
+	source.lead = raw[:end]
+	for i := range fields {
+		f := &fields[i]
+		f.start -= first
+		f.end -= first
+		next := eor - first
+		if i+1 < len(fields) {
+			next = fields[i+1].start - first
+		}
+		f.tagName = raw[f.start+1 : f.start+1+len(f.name)]
+		f.raw = raw[f.start:f.end]
+		f.after = raw[f.end:next]
+	}
+	source.fields = fields
+	source.eor = raw[eor-first:]
+	return source
+}
+
+// Whether a field is the last one with its name, which is the one whose
+// value the record holds
+func (source *recordSource) isLast(i int) bool {
+	for _, f := range source.fields[i+1:] {
+		if f.name == source.fields[i].name {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether a field of the record still has the value and type it was read
+// with
+func (source *recordSource) fieldUnchanged(r *baseADIFRecord, f *sourceField) bool {
+	value, ok := r.values[f.name]
+	if !ok || value != f.value {
+		return false
+	}
+	typecode, hasType := r.types[f.name]
+	return hasType == f.hasType && typecode == f.typecode
+}
+
+// Whether the record is exactly as it was read
+func (source *recordSource) unmodified(r *baseADIFRecord) bool {
+	names := 0
+	for i := range source.fields {
+		if !source.isLast(i) {
+			// An earlier value of a repeated field is lost
+			return false
+		}
+		if !source.fieldUnchanged(r, &source.fields[i]) {
+			return false
+		}
+		names++
+	}
+	return names == len(r.values)
+}
+
+func newHeaderSource(header *ADIFHeader, raw string, options sourceOptions) *headerSource {
+	return &headerSource{raw: raw, parsed: copyHeader(header), options: options}
+}
+
+// Copy of a header, without its source
+func copyHeader(header *ADIFHeader) ADIFHeader {
+	c := *header
+	c.source = nil
+	c.UserDefs = append([]UserDef(nil), header.UserDefs...)
+	c.comments = append([]Comment(nil), header.comments...)
+	c.AppFields = make(map[string]string, len(header.AppFields))
+	for name, value := range header.AppFields {
+		c.AppFields[name] = value
+	}
+	if header.appNames != nil {
+		c.appNames = make(map[string]string, len(header.appNames))
+		for name, cased := range header.appNames {
+			c.appNames[name] = cased
+		}
+	}
+	return c
+}
+
+// Whether the header is exactly as it was read
+func (source *headerSource) unmodified(header *ADIFHeader) bool {
+	return reflect.DeepEqual(copyHeader(header), source.parsed)
+}
+
+// Whether text read with the options is written unchanged by the writer,
+// which is the case if it is ASCII or if both count and encode characters
+// the same way
+func (writer *baseADIFWriter) canReuse(raw string, options sourceOptions) bool {
+	if !hasNonASCII(raw) {
+		return true
+	}
+	if options.charset != writer.config.charset || options.charset == CharsetAuto {
+		return false
+	}
+	runes := writer.config.lengthMode == LengthRunes && writer.config.charset == CharsetRaw
+	switch options.lengthMode {
+	case LengthBytes:
+		return !runes
+	case LengthRunes:
+		return runes
+	}
+	return false
+}
+
+// Write a record read in lossless mode, keeping the text of unchanged
+// fields.  New fields are written before the <eor>.
+func (writer *baseADIFWriter) writeSource(r *baseADIFRecord) error {
+	source := r.source
+	if source.unmodified(r) {
+		writer.started = true
+		_, err := writer.writer.WriteString(source.raw)
+		return err
+	}
+
+	// Encode changed and new values first, so nothing is written if one
+	// can't be
+	seen := make(map[string]bool, len(source.fields))
+	changed := make(map[string]string)
+	for i := range source.fields {
+		f := &source.fields[i]
+		seen[f.name] = true
+		if _, ok := r.values[f.name]; !ok || !source.isLast(i) || source.fieldUnchanged(r, f) {
+			continue
+		}
+		value, err := writer.encodeValue(r, f.name)
+		if err != nil {
+			return err
+		}
+		changed[f.name] = value
+	}
+	var added []string
+	for _, name := range writer.config.fieldOrder(r) {
+		if seen[name] {
+			continue
+		}
+		value, err := writer.encodeValue(r, name)
+		if err != nil {
+			return err
+		}
+		added = append(added, writer.serializeField(outputName(r, name), value, recordTypeCode(r, name)))
+	}
+
+	writer.started = true
+	w := writer.writer
+	w.WriteString(source.lead)
+	for i := range source.fields {
+		f := &source.fields[i]
+		if _, ok := r.values[f.name]; ok && source.isLast(i) {
+			if value, ok := changed[f.name]; ok {
+				w.WriteString(writer.serializeField(f.tagName, value, recordTypeCode(r, f.name)))
+			} else {
+				w.WriteString(f.raw)
+			}
+		}
+		w.WriteString(f.after)
+	}
+	for _, field := range added {
+		w.WriteString(field)
+	}
+	_, err := w.WriteString(source.eor)
+	return err
+}
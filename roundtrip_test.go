@@ -0,0 +1,114 @@
+package adifparser
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func roundTrip(t *testing.T, input []byte, modify func(ADIFRecord)) string {
+	reader := NewADIFReader(bytes.NewReader(input), WithLossless())
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	if err := writer.WriteHeader(*reader.Header()); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		record, err := reader.ReadRecord()
+		if err == io.EOF {
+			writer.WriteTrailer(reader.Trailer())
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modify != nil {
+			modify(record)
+		}
+		if err := writer.WriteRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Flush()
+	return buf.String()
+}
+
+func TestLosslessRoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("testdata/*.adi")
+	for _, name := range files {
+		input, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := roundTrip(t, input, nil); got != string(input) {
+			t.Fatalf("%s: expected\n%q\ngot\n%q", name, input, got)
+		}
+	}
+	input := "<CALL:4>W1AW <Freq:6:N>14.074\n\n<eor>\r\n// comment\n<call:5>KF4MD<EOR>"
+	if got := roundTrip(t, []byte(input), nil); got != input {
+		t.Fatalf("Expected %q, got %q", input, got)
+	}
+}
+
+func TestLosslessChangedRecord(t *testing.T) {
+	input := "Header\n<eoh>\n<CALL:4>W1AW <Freq:6:N>14.074 <zz_note:3>abc\n<band:3>20m<EOR>\n"
+	got := roundTrip(t, []byte(input), func(r ADIFRecord) {
+		r.SetValue("call", "KF4MD")
+		r.DeleteField("zz_note")
+		r.SetValue("mode", "FT8")
+	})
+	expected := "Header\n<eoh>\n<CALL:5>KF4MD <Freq:6:N>14.074 \n<band:3>20m<mode:3>FT8<EOR>\n"
+	if got != expected {
+		t.Fatalf("Expected %q, got %q", expected, got)
+	}
+
+	// A changed header is written again
+	reader := NewADIFReader(strings.NewReader(input), WithLossless())
+	header := *reader.Header()
+	header.ProgramID = "test"
+	var buf bytes.Buffer
+	writer := NewADIFWriter(&buf)
+	writer.WriteHeader(header)
+	writer.Flush()
+	if !strings.Contains(buf.String(), "<programid:4>test") {
+		t.Fatalf("Unexpected header %q", buf.String())
+	}
+}
+
+func TestLosslessWriterOptions(t *testing.T) {
+	input := "Café\n<eoh>\n<name_intl:5>José<eor>\n<call:4>W1AW<eor>\n"
+	for _, test := range []struct {
+		opts     []WriterOption
+		expected string
+	}{
+		{nil, input},
+		{[]WriterOption{WithOutputCharset(CharsetISO88591)},
+			"Caf\xe9\n<adif_ver:5>" + ADIFVersion + "\n<programid:10>adifparser\n<eoh>\n" +
+				"<name_intl:4>Jos\xe9<eor>\n<call:4>W1AW<eor>\n"},
+		{[]WriterOption{WithOutputLengthMode(LengthRunes)},
+			"Café\n<adif_ver:5>" + ADIFVersion + "\n<programid:10>adifparser\n<eoh>\n" +
+				"<name_intl:4>José<eor>\n<call:4>W1AW<eor>\n"},
+	} {
+		reader := NewADIFReader(strings.NewReader(input), WithLossless())
+		var buf bytes.Buffer
+		writer := NewADIFWriter(&buf, test.opts...)
+		if err := writer.WriteHeader(*reader.Header()); err != nil {
+			t.Fatal(err)
+		}
+		for record, err := range reader.All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.WriteRecord(record); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writer.Flush()
+		if buf.String() != test.expected {
+			t.Fatalf("Expected %q, got %q", test.expected, buf.String())
+		}
+	}
+}
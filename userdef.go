@@ -0,0 +1,172 @@
+package adifparser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Values of an enumeration specification, e.g. S, M and L for "{S,M,L}",
+// or nil if the field has none
+func (def UserDef) Enumeration() []string {
+	spec := specBody(def.Spec)
+	if spec == "" || strings.Contains(spec, ":") {
+		return nil
+	}
+	values := strings.Split(spec, ",")
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
+
+// Limits of a range specification, e.g. 5 and 20 for "{5:20}"
+func (def UserDef) Range() (min float64, max float64, ok bool) {
+	parts := strings.Split(specBody(def.Spec), ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	min, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	max, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// A specification without its braces
+func specBody(spec string) string {
+	spec = strings.TrimSpace(spec)
+	spec = strings.TrimPrefix(spec, "{")
+	spec = strings.TrimSuffix(spec, "}")
+	return strings.TrimSpace(spec)
+}
+
+// Get the declaration of a user-defined field, ignoring case
+func (h *ADIFHeader) GetUserDef(name string) (UserDef, bool) {
+	for _, def := range h.UserDefs {
+		if strings.EqualFold(def.Name, name) {
+			return def, true
+		}
+	}
+	return UserDef{}, false
+}
+
+// Declarations by lowercase field name
+func userDefMap(defs []UserDef) map[string]UserDef {
+	if len(defs) == 0 {
+		return nil
+	}
+	m := make(map[string]UserDef, len(defs))
+	for _, def := range defs {
+		m[strings.ToLower(def.Name)] = def
+	}
+	return m
+}
+
+// Declarations of the file a record was read from
+func recordUserDefs(r ADIFRecord) map[string]UserDef {
+	if br, ok := r.(*baseADIFRecord); ok {
+		return br.userDefs
+	}
+	return nil
+}
+
+// Get the data type indicator of a field: the explicit one, or else the
+// one in its USERDEF declaration
+func declaredTypeCode(r ADIFRecord, name string) byte {
+	if code := recordTypeCode(r, name); code != 0 {
+		return code
+	}
+	return recordUserDefs(r)[name].TypeCode
+}
+
+// Whether a field is neither standard nor application-defined
+func isUserDefField(name string) bool {
+	if isStandardADIFField(name) {
+		return false
+	}
+	_, _, app := splitAppFieldName(name)
+	return !app
+}
+
+// Bytes held back by WithOutputUserDefs if no limit is given
+const defaultUserDefLimit = 1 << 20
+
+// Output held back until it is flushed, so that the header can declare
+// the custom fields of the records
+type userDefOutput struct {
+	// Where the output goes once the header is written
+	dest *bufio.Writer
+	// Output held back, and the number of bytes after which the header
+	// is written
+	buf   bytes.Buffer
+	limit int
+	// Header to write, or nil if none has been given
+	header *ADIFHeader
+	// Declarations of the custom fields written, in the order seen
+	defs []UserDef
+	seen map[string]bool
+}
+
+func newUserDefOutput(w io.Writer, limit int) *userDefOutput {
+	if limit < 1 {
+		limit = defaultUserDefLimit
+	}
+	return &userDefOutput{dest: bufio.NewWriter(w), limit: limit, seen: make(map[string]bool)}
+}
+
+// Whether more than the limit is held back, counting the bytes not yet
+// flushed from w
+func (out *userDefOutput) full(w *bufio.Writer) bool {
+	return out.buf.Len()+w.Buffered() > out.limit
+}
+
+// Note the custom fields of a record written
+func (out *userDefOutput) add(r ADIFRecord) {
+	declared := recordUserDefs(r)
+	for _, name := range sortFields(r.GetFields()) {
+		if out.seen[name] || !isUserDefField(name) {
+			continue
+		}
+		out.seen[name] = true
+		def, ok := declared[name]
+		if !ok {
+			def = UserDef{Name: name}
+		}
+		if code := recordTypeCode(r, name); code != 0 {
+			def.TypeCode = code
+		}
+		if def.TypeCode == 0 {
+			def.TypeCode = 'S'
+		}
+		out.defs = append(out.defs, def)
+	}
+}
+
+// The header with declarations added for the custom fields it lacks
+func (out *userDefOutput) declare(header ADIFHeader) ADIFHeader {
+	next := 1
+	for _, def := range header.UserDefs {
+		if def.ID >= next {
+			next = def.ID + 1
+		}
+	}
+	defs := header.UserDefs
+	for _, def := range out.defs {
+		if _, ok := header.GetUserDef(def.Name); ok {
+			continue
+		}
+		if len(defs) == len(header.UserDefs) {
+			// Don't change the caller's slice
+			defs = append([]UserDef(nil), defs...)
+		}
+		def.ID = next
+		next++
+		defs = append(defs, def)
+	}
+	header.UserDefs = defs
+	return header
+}
@@ -0,0 +1,379 @@
+package adifparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity of a validation issue
+type Severity int
+
+const (
+	// The record may be accepted, but should be corrected
+	SeverityWarning Severity = iota
+	// The record does not conform to the ADIF specification
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// A problem found while validating a record
+type ValidationIssue struct {
+	// Field name (lowercase)
+	Field    string
+	Severity Severity
+	Message  string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Field, i.Message)
+}
+
+// Allowed range of integer fields
+type intRange struct {
+	min int
+	max int
+}
+
+var fieldRanges = map[string]intRange{
+	"cqz":         {1, 40},
+	"my_cq_zone":  {1, 40},
+	"ituz":        {1, 90},
+	"my_itu_zone": {1, 90},
+	"k_index":     {0, 9},
+	"a_index":     {0, 400},
+	"sfi":         {0, 300},
+	"ant_az":      {0, 360},
+	"ant_el":      {-90, 90},
+}
+
+// Validate a record against the ADIF specification and the USERDEF
+// declarations of the file it was read from
+func Validate(r ADIFRecord) []ValidationIssue {
+	return validate(r, recordUserDefs(r))
+}
+
+// Validate a record against the ADIF specification and the USERDEF
+// declarations of a header
+func ValidateWithHeader(r ADIFRecord, header *ADIFHeader) []ValidationIssue {
+	return validate(r, userDefMap(header.UserDefs))
+}
+
+func validate(r ADIFRecord, userDefs map[string]UserDef) []ValidationIssue {
+	v := &validator{record: r, userDefs: userDefs}
+	for _, name := range sortFields(r.GetFields()) {
+		value, _ := r.GetValue(name)
+		v.checkField(name, value)
+	}
+	v.checkBand("freq", "band")
+	v.checkBand("freq_rx", "band_rx")
+	v.checkSubmode()
+	return v.issues
+}
+
+// Validation state for a single record
+type validator struct {
+	record ADIFRecord
+	// USERDEF declarations, by lowercase name
+	userDefs map[string]UserDef
+	issues   []ValidationIssue
+}
+
+func (v *validator) addIssue(field string, severity Severity, format string, args ...interface{}) {
+	v.issues = append(v.issues, ValidationIssue{
+		Field:    field,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkField(name, value string) {
+	if value == "" {
+		// An empty field is the same as an absent one
+		return
+	}
+	info, standard := ADIFfieldInfo[name]
+	def, declared := v.userDefs[name]
+	datatype := ADIFString
+	code := recordTypeCode(v.record, name)
+	if declared && code == 0 {
+		code = def.TypeCode
+	}
+	if standard {
+		datatype = info.DataType
+		if info.ImportOnly {
+			v.addIssue(name, SeverityWarning, "field is import-only")
+		}
+	} else if code != 0 {
+		if t, ok := typeCodeMap[code]; ok {
+			datatype = t
+		} else {
+			v.addIssue(name, SeverityError, "unknown data type indicator %q", code)
+		}
+	}
+
+	if msg := checkDataType(datatype, value); msg != "" {
+		v.addIssue(name, SeverityError, "invalid %s %q: %s",
+			adifTypeNames[datatype], value, msg)
+		return
+	}
+
+	if standard && info.Enumeration != "" {
+		v.checkEnumeration(name, info.Enumeration, value)
+	}
+	if !standard && declared {
+		v.checkUserDef(name, def, value)
+	}
+	if limits, ok := fieldRanges[name]; ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil &&
+			(n < limits.min || n > limits.max) {
+			v.addIssue(name, SeverityError, "%d is outside the range %d to %d",
+				n, limits.min, limits.max)
+		}
+	}
+}
+
+// Check a value against the enumeration or range of its declaration
+func (v *validator) checkUserDef(name string, def UserDef, value string) {
+	if values := def.Enumeration(); values != nil {
+		for _, allowed := range values {
+			if strings.EqualFold(allowed, strings.TrimSpace(value)) {
+				return
+			}
+		}
+		v.addIssue(name, SeverityError, "%q is not one of %s", value, def.Spec)
+	}
+	if min, max, ok := def.Range(); ok {
+		n, err := parseADIFNumber(strings.TrimSpace(value))
+		if err != nil {
+			v.addIssue(name, SeverityError, "%q is not a number in the range %s", value, def.Spec)
+		} else if n < min || n > max {
+			v.addIssue(name, SeverityError, "%v is outside the range %s", n, def.Spec)
+		}
+	}
+}
+
+func (v *validator) checkEnumeration(name, enumeration, value string) {
+	upper := strings.ToUpper(value)
+	switch enumeration {
+	case "Band":
+		if _, ok := adifBands[strings.ToLower(value)]; !ok {
+			v.addIssue(name, SeverityError, "unknown band %q", value)
+		}
+	case "Mode":
+		if _, ok := adifModes[upper]; ok {
+			return
+		}
+		if adifImportOnlyModes[upper] {
+			v.addIssue(name, SeverityWarning, "mode %q is import-only, use it as a submode", value)
+		} else {
+			v.addIssue(name, SeverityError, "unknown mode %q", value)
+		}
+	case "Submode":
+		if _, ok := submodeParent(value); !ok {
+			v.addIssue(name, SeverityError, "unknown submode %q", value)
+		}
+	case "DXCC Entity Code":
+		if !isDigits(value) {
+			v.addIssue(name, SeverityError, "invalid DXCC entity code %q", value)
+		}
+	default:
+		values, ok := adifEnumerations[enumeration]
+		if !ok {
+			// Not checked
+			return
+		}
+		if current, ok := values[upper]; !ok {
+			v.addIssue(name, SeverityError, "%q is not a valid %s value", value, enumeration)
+		} else if !current {
+			v.addIssue(name, SeverityWarning, "%s value %q is import-only", enumeration, value)
+		}
+	}
+}
+
+// Check that the frequency lies inside the band
+func (v *validator) checkBand(freqField, bandField string) {
+	freq, err := v.record.GetFloat(freqField)
+	if err != nil {
+		return
+	}
+	band, err := v.record.GetValue(bandField)
+	if err != nil {
+		return
+	}
+	limits, ok := adifBands[strings.ToLower(band)]
+	if !ok {
+		return
+	}
+	if freq < limits.lower || freq > limits.upper {
+		v.addIssue(freqField, SeverityError, "frequency %v MHz is outside the %s band",
+			freq, strings.ToLower(band))
+	}
+}
+
+// Check that the submode belongs to the mode
+func (v *validator) checkSubmode() {
+	submode, err := v.record.GetValue("submode")
+	if err != nil {
+		return
+	}
+	mode, err := v.record.GetValue("mode")
+	if err != nil {
+		v.addIssue("submode", SeverityError, "submode without mode")
+		return
+	}
+	parent, ok := submodeParent(submode)
+	if ok && !strings.EqualFold(parent, mode) {
+		v.addIssue("submode", SeverityError, "submode %q belongs to mode %s, not %s",
+			submode, parent, strings.ToUpper(mode))
+	}
+}
+
+// Check a value against a data type, returning a description of the
+// problem or an empty string
+func checkDataType(datatype int, value string) string {
+	switch datatype {
+	case ADIFBoolean:
+		if _, err := parseADIFBoolean(value); err != nil {
+			return "expected Y or N"
+		}
+	case ADIFNumber:
+		if _, err := parseADIFNumber(value); err != nil {
+			return "expected a decimal number"
+		}
+	case ADIFInteger:
+		if !isDigits(strings.TrimPrefix(value, "-")) {
+			return "expected an integer"
+		}
+	case ADIFPositiveInteger:
+		if n, err := strconv.Atoi(value); err != nil || !isDigits(value) || n < 1 {
+			return "expected a positive integer"
+		}
+	case ADIFDate:
+		if t, err := parseADIFDate(value); err != nil {
+			return "expected YYYYMMDD"
+		} else if t.Year() < 1930 {
+			return "dates before 1930 are not allowed"
+		}
+	case ADIFTime:
+		if _, err := parseADIFTime(value); err != nil {
+			return "expected HHMM or HHMMSS"
+		}
+	case ADIFLocation:
+		if _, err := parseADIFLocation(value); err != nil {
+			return "expected XDDD MM.MMM"
+		}
+	case ADIFGridSquare:
+		if !isGridSquare(value) {
+			return "expected a 2, 4, 6 or 8 character Maidenhead locator"
+		}
+	case ADIFGridSquareExt:
+		if !isGridSquareExt(value) {
+			return "expected a 2, 4 or 6 character locator extension"
+		}
+	case ADIFGridSquareList:
+		for _, grid := range strings.Split(value, ",") {
+			if !isGridSquare(grid) {
+				return "expected a comma-separated list of locators"
+			}
+		}
+	case ADIFIOTARefNo:
+		if !isIOTARef(value) {
+			return "expected CC-XXX"
+		}
+	case ADIFCharacter, ADIFDigit:
+		if len(value) != 1 {
+			return "expected a single character"
+		}
+		if datatype == ADIFDigit && !isDigits(value) {
+			return "expected a digit"
+		}
+	case ADIFIntlString, ADIFIntlMultilineString:
+		if !utf8.ValidString(value) {
+			return "invalid UTF-8"
+		}
+	case ADIFMultilineString:
+		if !isASCII(value, true) {
+			return "non-ASCII characters"
+		}
+	default:
+		if !isASCII(value, false) {
+			return "non-ASCII characters"
+		}
+	}
+	return ""
+}
+
+// Whether a string only contains printable ASCII, plus CR and LF if
+// multiline is set
+func isASCII(s string, multiline bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if multiline && (c == '\r' || c == '\n') {
+			continue
+		}
+		if c < 32 || c > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check a Maidenhead locator of 2, 4, 6 or 8 characters
+func isGridSquare(s string) bool {
+	if len(s) == 0 || len(s) > 8 || len(s)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := charToUpper(s[i])
+		switch i {
+		case 0, 1:
+			if c < 'A' || c > 'R' {
+				return false
+			}
+		case 4, 5:
+			if c < 'A' || c > 'X' {
+				return false
+			}
+		default:
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Check a locator extension (characters 9 onwards) of 2, 4 or 6 characters
+func isGridSquareExt(s string) bool {
+	if len(s) == 0 || len(s) > 6 || len(s)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := charToUpper(s[i])
+		if i == 2 || i == 3 {
+			if c < '0' || c > '9' {
+				return false
+			}
+		} else if c < 'A' || c > 'X' {
+			return false
+		}
+	}
+	return true
+}
+
+// Check an IOTA reference, e.g. NA-001
+func isIOTARef(s string) bool {
+	if len(s) != 6 || s[2] != '-' || !isDigits(s[3:]) {
+		return false
+	}
+	_, ok := adifEnumerations["Continent"][strings.ToUpper(s[:2])]
+	return ok
+}
@@ -0,0 +1,128 @@
+package adifparser
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateClean(t *testing.T) {
+	for _, file := range []string{"wsjtx.adi", "xlog.adi"} {
+		f, err := os.Open(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		reader := NewADIFReader(f)
+		for rec, err := reader.ReadRecord(); err != io.EOF; rec, err = reader.ReadRecord() {
+			if err != nil {
+				t.Fatalf("input %s: %v", file, err)
+			}
+			for _, issue := range Validate(rec) {
+				if issue.Severity == SeverityError {
+					t.Fatalf("input %s record %d: %v", file, reader.RecordCount(), issue)
+				}
+			}
+		}
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	cases := []struct {
+		field    string
+		value    string
+		severity Severity
+	}{
+		{"qso_date", "20151323", SeverityError},
+		{"qso_date", "19291231", SeverityError},
+		{"time_on", "2460", SeverityError},
+		{"freq", "14,074", SeverityError},
+		{"lat", "N91 00.000", SeverityError},
+		{"gridsquare", "ZZ12", SeverityError},
+		{"swl", "X", SeverityError},
+		{"band", "11m", SeverityError},
+		{"mode", "FOO", SeverityError},
+		{"mode", "PSK31", SeverityWarning},
+		{"qsl_rcvd", "Q", SeverityError},
+		{"qsl_rcvd", "V", SeverityWarning},
+		{"prop_mode", "XX", SeverityError},
+		{"cont", "XX", SeverityError},
+		{"cqz", "41", SeverityError},
+		{"ituz", "0", SeverityError},
+		{"iota", "NA-01", SeverityError},
+		{"name", "J\xc3\xbcrgen", SeverityError},
+		{"ve_prov", "ON", SeverityWarning},
+	}
+	for _, c := range cases {
+		record := NewADIFRecord()
+		record.SetValue(c.field, c.value)
+		issues := Validate(record)
+		if len(issues) != 1 {
+			t.Fatalf("%s=%q: expected 1 issue, got %v", c.field, c.value, issues)
+		}
+		if issues[0].Field != c.field || issues[0].Severity != c.severity {
+			t.Fatalf("%s=%q: unexpected issue %v", c.field, c.value, issues[0])
+		}
+	}
+}
+
+func TestValidateCrossField(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetValue("band", "20m")
+	record.SetValue("freq", "14.074")
+	record.SetValue("mode", "MFSK")
+	record.SetValue("submode", "FT4")
+	record.SetValue("name_intl", "J\xc3\xbcrgen")
+	record.SetValue("cqz", "5")
+	if issues := Validate(record); len(issues) != 0 {
+		t.Fatalf("Expected no issues, got %v", issues)
+	}
+
+	record.SetValue("freq", "7.074")
+	record.SetValue("submode", "PSK31")
+	issues := Validate(record)
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %v", issues)
+	}
+	if issues[0].Field != "freq" || issues[1].Field != "submode" {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+}
+
+func TestValidateTypedUserField(t *testing.T) {
+	record := NewADIFRecord()
+	record.SetTypedValue("mycount", "abc", 'N')
+	issues := Validate(record)
+	if len(issues) != 1 || issues[0].Severity != SeverityError {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+}
+
+func TestValidateUserDefs(t *testing.T) {
+	header := &ADIFHeader{UserDefs: []UserDef{
+		{ID: 1, Name: "SweaterSize", TypeCode: 'E', Spec: "{S,M,L}"},
+		{ID: 2, Name: "ShoeSize", TypeCode: 'N', Spec: "{5:20}"},
+	}}
+	record := NewADIFRecord()
+	record.SetValue("sweatersize", "m")
+	record.SetValue("shoesize", "9.5")
+	if issues := ValidateWithHeader(record, header); len(issues) != 0 {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+	record.SetValue("sweatersize", "XL")
+	record.SetValue("shoesize", "21")
+	issues := ValidateWithHeader(record, header)
+	if len(issues) != 2 || issues[0].Field != "shoesize" || issues[1].Field != "sweatersize" {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+	record.SetValue("shoesize", "big")
+	issues = ValidateWithHeader(record, header)
+	if len(issues) != 2 || issues[0].Field != "shoesize" {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+	// Without the declarations, the fields are free text
+	if issues := Validate(record); len(issues) != 0 {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+}